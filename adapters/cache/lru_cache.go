@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+)
+
+// LRUCache implements the SolutionCache interface, evicting the least recently used hash once full.
+// When a file path is set, the cache is loaded from it on creation and written back on every change.
+type LRUCache struct {
+	mux      sync.Mutex
+	capacity int
+	entries  *list.List               // Cached solutions, most recently used first
+	index    map[string]*list.Element // Maps hashes to their entry
+	filePath string
+}
+
+// NewLRUCache creates a cache holding at most capacity hashes, optionally persisted to filePath.
+func NewLRUCache(capacity int, filePath string) (*LRUCache, error) {
+	if capacity < 1 {
+		return nil, fmt.Errorf("cache capacity must be at least 1, got %d", capacity)
+	}
+
+	c := &LRUCache{
+		capacity: capacity,
+		entries:  list.New(),
+		index:    make(map[string]*list.Element),
+		filePath: filePath,
+	}
+	if filePath != "" {
+		if err := c.load(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *LRUCache) Get(hash string) (string, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	element, ok := c.index[hash]
+	if !ok {
+		return "", false
+	}
+	c.entries.MoveToFront(element)
+	return element.Value.(ports.CachedSolution).Plaintext, true
+}
+
+func (c *LRUCache) Put(hash, plaintext string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.put(hash, plaintext)
+	c.save()
+}
+
+func (c *LRUCache) List() []ports.CachedSolution {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	solutions := make([]ports.CachedSolution, 0, c.entries.Len())
+	for e := c.entries.Front(); e != nil; e = e.Next() {
+		solutions = append(solutions, e.Value.(ports.CachedSolution))
+	}
+	return solutions
+}
+
+func (c *LRUCache) Delete(hash string) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	element, ok := c.index[hash]
+	if !ok {
+		return false
+	}
+	c.entries.Remove(element)
+	delete(c.index, hash)
+	c.save()
+	return true
+}
+
+func (c *LRUCache) Purge() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.entries.Init()
+	c.index = make(map[string]*list.Element)
+	c.save()
+}
+
+// put adds or refreshes an entry, evicting the least recently used one when full.
+func (c *LRUCache) put(hash, plaintext string) {
+	solution := ports.CachedSolution{Hash: hash, Plaintext: plaintext}
+	if element, ok := c.index[hash]; ok {
+		element.Value = solution
+		c.entries.MoveToFront(element)
+		return
+	}
+
+	c.index[hash] = c.entries.PushFront(solution)
+	if c.entries.Len() > c.capacity {
+		oldest := c.entries.Back()
+		c.entries.Remove(oldest)
+		delete(c.index, oldest.Value.(ports.CachedSolution).Hash)
+	}
+}
+
+// load fills the cache from its file, if it exists.
+func (c *LRUCache) load() error {
+	data, err := os.ReadFile(c.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read cache file %s: %v", c.filePath, err)
+	}
+
+	var solutions []ports.CachedSolution
+	if err := json.Unmarshal(data, &solutions); err != nil {
+		return fmt.Errorf("failed to decode cache file %s: %v", c.filePath, err)
+	}
+
+	// The file lists the most recently used first, insert from the oldest
+	for i := len(solutions) - 1; i >= 0; i-- {
+		c.put(solutions[i].Hash, solutions[i].Plaintext)
+	}
+	log.Printf("Loaded %d cached solutions from %s\n", c.entries.Len(), c.filePath)
+	return nil
+}
+
+// save writes the cache to its file, replacing it atomically.
+func (c *LRUCache) save() {
+	if c.filePath == "" {
+		return
+	}
+
+	solutions := make([]ports.CachedSolution, 0, c.entries.Len())
+	for e := c.entries.Front(); e != nil; e = e.Next() {
+		solutions = append(solutions, e.Value.(ports.CachedSolution))
+	}
+	data, err := json.Marshal(solutions)
+	if err != nil {
+		log.Printf("Failed to encode cache: %v\n", err)
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.filePath), filepath.Base(c.filePath)+".*")
+	if err != nil {
+		log.Printf("Failed to write cache file %s: %v\n", c.filePath, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		log.Printf("Failed to write cache file %s: %v\n", c.filePath, err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		log.Printf("Failed to write cache file %s: %v\n", c.filePath, err)
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.filePath); err != nil {
+		log.Printf("Failed to replace cache file %s: %v\n", c.filePath, err)
+	}
+}
@@ -1,13 +1,108 @@
 package docker
 
-import "log"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
 
-func handleUnexpectedError(err error) {
-	if err != nil {
-		log.Fatalf("Unexpected error: %v\n", err)
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"go.opentelemetry.io/otel/trace"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/metrics"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/tracing"
+)
+
+var (
+	// ErrServiceNotFound is returned when the worker service does not exist.
+	ErrServiceNotFound = errors.New("service not found")
+
+	// ErrDaemonUnavailable is returned when the Docker daemon cannot be reached.
+	ErrDaemonUnavailable = fmt.Errorf("Docker daemon unavailable: %w", ports.ErrOrchestratorUnavailable)
+
+	// ErrVersionConflict is returned when the service was updated by someone else meanwhile.
+	ErrVersionConflict = errors.New("service version conflict")
+
+	// ErrContainerNotFound is returned when a worker container does not exist.
+	ErrContainerNotFound = errors.New("container not found")
+
+	// ErrNetworkNotFound is returned when a network of the worker service does not exist.
+	ErrNetworkNotFound = errors.New("network not found")
+)
+
+// notFoundErrors maps the kind of object a call is about, the prefix of its operation name,
+// to the error returned when that object does not exist.
+var notFoundErrors = map[string]error{
+	"service":   ErrServiceNotFound,
+	"task":      ErrServiceNotFound, // Tasks are listed for the worker service
+	"container": ErrContainerNotFound,
+	"network":   ErrNetworkNotFound,
+}
+
+const (
+	retryAttempts = 4                      // Calls made before giving up
+	retryDelay    = 250 * time.Millisecond // Delay before the first retry, doubled after each attempt
+)
+
+// classify wraps an error of the Docker client, returned by the given operation, in the matching typed error.
+func classify(operation string, err error) error {
+	kind, _, _ := strings.Cut(operation, "_")
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrServiceNotFound), errors.Is(err, ErrDaemonUnavailable), errors.Is(err, ErrVersionConflict),
+		errors.Is(err, ErrContainerNotFound), errors.Is(err, ErrNetworkNotFound):
+		return err // Already classified
+	case client.IsErrConnectionFailed(err), errdefs.IsUnavailable(err), errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %v", ErrDaemonUnavailable, err)
+	case errdefs.IsConflict(err), strings.Contains(err.Error(), "update out of sequence"):
+		return fmt.Errorf("%w: %v", ErrVersionConflict, err)
+	case errdefs.IsNotFound(err) && notFoundErrors[kind] != nil:
+		return fmt.Errorf("%w: %v", notFoundErrors[kind], err)
+	}
+	return err
+}
+
+// retryable reports whether a failed call may succeed when made again as is.
+func retryable(err error) bool {
+	return errors.Is(err, ErrDaemonUnavailable)
+}
+
+// call makes a Docker API call, traced and counted as the given operation, and returns its classified error.
+// When retry is set, calls failing because of a retryable error are made again with an exponential backoff.
+// Only idempotent calls, or calls re-reading what they update, may set it: a call creating or starting
+// something may have taken effect even though it failed, for instance when the daemon answered too late.
+func call(ctx context.Context, operation string, retry bool, fn func(ctx context.Context) error) error {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		callCtx, done := observe(ctx, operation)
+		err := done(classify(operation, fn(callCtx)))
+		if err == nil || !retry || !retryable(err) || attempt == retryAttempts {
+			return err
+		}
+
+		log.Printf("Docker %s failed (attempt %d/%d), retrying in %v: %v\n", operation, attempt, retryAttempts, delay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
 	}
 }
 
-func handleUnfoundServiceName(serviceName string) {
-	log.Fatalf("service %s not found", serviceName)
+// observe starts the span of a Docker API call. The returned function ends it,
+// counting the call as failed when given an error, and returns the error unchanged.
+func observe(ctx context.Context, operation string) (context.Context, func(error) error) {
+	ctx, span := tracing.Tracer().Start(ctx, "docker."+operation, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, func(err error) error {
+		if err != nil {
+			metrics.DockerAPIErrors.WithLabelValues(operation).Inc()
+		}
+		tracing.End(span, err)
+		return err
+	}
 }
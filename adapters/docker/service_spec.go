@@ -0,0 +1,171 @@
+package docker
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/swarm"
+)
+
+// urlPlaceholder is replaced by the coordinator URL in the worker command and arguments.
+const urlPlaceholder = "{url}"
+
+// ServiceConfig describes the Swarm service running the workers.
+type ServiceConfig struct {
+	Name           string
+	Image          string
+	Command        []string // Overrides the entrypoint of the image when set
+	Args           []string // Occurrences of {url} are replaced by CoordinatorURL
+	CoordinatorURL string   // WebSocket URL the workers connect to
+	Env            []string // KEY=VALUE pairs added to the environment of the workers
+	Networks       []string
+	Constraints    []string // Placement constraints, such as node.role==worker
+	Labels         map[string]string
+
+	CPULimit          int64 // In billionths of a CPU, 0 for none
+	MemoryLimit       int64 // In bytes, 0 for none
+	CPUReservation    int64 // In billionths of a CPU, 0 for none
+	MemoryReservation int64 // In bytes, 0 for none
+}
+
+// DefaultServiceConfig returns the configuration of the hash_extractor workers on the host network.
+func DefaultServiceConfig() ServiceConfig {
+	return ServiceConfig{
+		Name:           "md5onelettertest",
+		Image:          "servuc/hash_extractor:latest",
+		Args:           []string{"s", urlPlaceholder},
+		CoordinatorURL: "ws://127.0.0.1:8080/ws",
+		Networks:       []string{"host"},
+	}
+}
+
+// spec builds the service specification of the configuration, with the given number of replicas.
+func (c ServiceConfig) spec(replicas uint64) swarm.ServiceSpec {
+	env := []string{
+		"TASK_SLOT={{.Task.Slot}}", // Lets workers identify themselves to the coordinator
+		"COORDINATOR_URL=" + c.CoordinatorURL,
+	}
+	env = append(env, c.Env...)
+
+	networks := make([]swarm.NetworkAttachmentConfig, 0, len(c.Networks))
+	for _, network := range c.Networks {
+		networks = append(networks, swarm.NetworkAttachmentConfig{Target: network})
+	}
+
+	spec := swarm.ServiceSpec{
+		Annotations: swarm.Annotations{
+			Name:   c.Name,
+			Labels: c.Labels,
+		},
+		TaskTemplate: swarm.TaskSpec{
+			ContainerSpec: &swarm.ContainerSpec{
+				Image:   c.Image,
+				Command: c.withURL(c.Command),
+				Args:    c.withURL(c.Args),
+				Env:     env,
+			},
+			RestartPolicy: &swarm.RestartPolicy{
+				Condition: swarm.RestartPolicyConditionAny,
+			},
+			Networks: networks,
+		},
+		Mode: swarm.ServiceMode{
+			Replicated: &swarm.ReplicatedService{
+				Replicas: uint64Ptr(replicas),
+			},
+		},
+	}
+	if len(c.Constraints) > 0 {
+		spec.TaskTemplate.Placement = &swarm.Placement{Constraints: c.Constraints}
+	}
+	if c.CPULimit != 0 || c.MemoryLimit != 0 || c.CPUReservation != 0 || c.MemoryReservation != 0 {
+		spec.TaskTemplate.Resources = &swarm.ResourceRequirements{}
+		if c.CPULimit != 0 || c.MemoryLimit != 0 {
+			spec.TaskTemplate.Resources.Limits = &swarm.Limit{NanoCPUs: c.CPULimit, MemoryBytes: c.MemoryLimit}
+		}
+		if c.CPUReservation != 0 || c.MemoryReservation != 0 {
+			spec.TaskTemplate.Resources.Reservations = &swarm.Resources{NanoCPUs: c.CPUReservation, MemoryBytes: c.MemoryReservation}
+		}
+	}
+	return spec
+}
+
+// withURL replaces the {url} placeholder by the coordinator URL.
+func (c ServiceConfig) withURL(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	replaced := make([]string, len(args))
+	for i, arg := range args {
+		replaced[i] = strings.ReplaceAll(arg, urlPlaceholder, c.CoordinatorURL)
+	}
+	return replaced
+}
+
+// drift lists the fields of the current service specification that differ from the desired one.
+// Replicas are left out, they belong to the scaling policy.
+func drift(current, desired swarm.ServiceSpec) []string {
+	var fields []string
+	check := func(field string, equal bool) {
+		if !equal {
+			fields = append(fields, field)
+		}
+	}
+
+	currentContainer := current.TaskTemplate.ContainerSpec
+	if currentContainer == nil {
+		currentContainer = &swarm.ContainerSpec{}
+	}
+	desiredContainer := desired.TaskTemplate.ContainerSpec
+	check("image", imageName(currentContainer.Image) == imageName(desiredContainer.Image))
+	check("command", slices.Equal(currentContainer.Command, desiredContainer.Command))
+	check("args", slices.Equal(currentContainer.Args, desiredContainer.Args))
+	check("env", slices.Equal(currentContainer.Env, desiredContainer.Env))
+	check("labels", maps.Equal(current.Labels, desired.Labels))
+	check("networks", slices.Equal(networkTargets(current), networkTargets(desired)))
+	check("constraints", slices.Equal(constraints(current.TaskTemplate.Placement), constraints(desired.TaskTemplate.Placement)))
+	check("resources", resources(current.TaskTemplate.Resources) == resources(desired.TaskTemplate.Resources))
+	return fields
+}
+
+// imageName strips the digest Swarm pins the image to when creating the service.
+func imageName(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	return name
+}
+
+// networkTargets returns the networks a service is attached to.
+func networkTargets(spec swarm.ServiceSpec) []string {
+	attachments := spec.TaskTemplate.Networks
+	if len(attachments) == 0 {
+		attachments = spec.Networks // Deprecated location, still filled by older clients
+	}
+	targets := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		targets = append(targets, attachment.Target)
+	}
+	return targets
+}
+
+// constraints returns the placement constraints of a service, if any.
+func constraints(placement *swarm.Placement) []string {
+	if placement == nil {
+		return nil
+	}
+	return placement.Constraints
+}
+
+// resources summarizes the limits and reservations of a service so they can be compared.
+func resources(requirements *swarm.ResourceRequirements) string {
+	var limits swarm.Limit
+	var reservations swarm.Resources
+	if requirements != nil && requirements.Limits != nil {
+		limits = *requirements.Limits
+	}
+	if requirements != nil && requirements.Reservations != nil {
+		reservations = *requirements.Reservations
+	}
+	return fmt.Sprintf("limits %d/%d reservations %d/%d", limits.NanoCPUs, limits.MemoryBytes, reservations.NanoCPUs, reservations.MemoryBytes)
+}
@@ -2,9 +2,12 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/swarm"
 	"github.com/docker/docker/client"
 	"io"
@@ -12,15 +15,22 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"strings"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
 )
 
+var _ ports.WorkerOrchestrator = (*Adapter)(nil)
+
 type Adapter struct {
 	client                  *client.Client
 	serviceName             string
+	config                  ServiceConfig
 	containerRestartTimeout int
 }
 
-func New(serviceName string) (*Adapter, error) {
+// New connects to the Docker daemon, initializing a Swarm if needed, and makes sure the worker service
+// matches the configuration: it is created when missing, and updated when its spec drifted.
+func New(config ServiceConfig) (*Adapter, error) {
 	// Initialize Docker client
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
@@ -52,62 +62,85 @@ func New(serviceName string) (*Adapter, error) {
 		log.Println("Swarm initialized successfully.")
 	}
 
-	// Check if the service exists
-	services, err := cli.ServiceList(ctx, types.ServiceListOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list services: %v", err)
+	d := &Adapter{
+		client:                  cli,
+		serviceName:             config.Name,
+		config:                  config,
+		containerRestartTimeout: timeout,
 	}
 
-	serviceExists := false
-	for _, service := range services {
-		if service.Spec.Name == serviceName {
-			serviceExists = true
-			break
+	// Create the service if it doesn't exist, reconcile it otherwise
+	service, err := d.GetServiceDetails(ctx)
+	if errors.Is(err, ErrServiceNotFound) {
+		log.Printf("Service %s not found. Creating service...\n", d.serviceName)
+
+		err = call(ctx, "service_create", false, func(ctx context.Context) error {
+			_, err := cli.ServiceCreate(ctx, config.spec(1), types.ServiceCreateOptions{}) // Start with 1 replica
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create service: %w", err)
 		}
+
+		log.Printf("Service %s created successfully.\n", d.serviceName)
+		return d, nil
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.reconcile(ctx, service); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
 
-	// Create the service if it doesn't exist
-	if !serviceExists {
-		log.Printf("Service %s not found. Creating service...\n", serviceName)
-
-		serviceSpec := swarm.ServiceSpec{
-			Annotations: swarm.Annotations{
-				Name: serviceName,
-			},
-			TaskTemplate: swarm.TaskSpec{
-				ContainerSpec: &swarm.ContainerSpec{
-					Image: "servuc/hash_extractor:latest",
-					Args:  []string{"s", "ws://127.0.0.1:8080/ws"},
-				},
-				RestartPolicy: &swarm.RestartPolicy{
-					Condition: swarm.RestartPolicyConditionAny,
-				},
-				Networks: []swarm.NetworkAttachmentConfig{
-					{Target: "host"},
-				},
-			},
-			Mode: swarm.ServiceMode{
-				Replicated: &swarm.ReplicatedService{
-					Replicas: uint64Ptr(1), // Start with 1 replica
-				},
-			},
+// reconcile updates the worker service when its spec drifted from the configuration, keeping its replicas.
+func (d *Adapter) reconcile(ctx context.Context, service *swarm.Service) error {
+	current := service.Spec
+	current.TaskTemplate.Networks = d.namedNetworks(ctx, current.TaskTemplate.Networks)
+	fields := drift(current, d.config.spec(0))
+	if len(fields) == 0 {
+		log.Printf("Service %s already exists and matches the configuration.\n", d.serviceName)
+		return nil
+	}
+
+	log.Printf("Service %s drifted from the configuration (%s), updating it...\n", d.serviceName, strings.Join(fields, ", "))
+	err := d.updateService(ctx, func(spec *swarm.ServiceSpec) error {
+		replicas := uint64(1)
+		if spec.Mode.Replicated != nil && spec.Mode.Replicated.Replicas != nil {
+			replicas = *spec.Mode.Replicated.Replicas
 		}
+		*spec = d.config.spec(replicas)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reconcile service %s: %w", d.serviceName, err)
+	}
 
-		_, err := cli.ServiceCreate(ctx, serviceSpec, types.ServiceCreateOptions{})
+	log.Printf("Service %s updated successfully.\n", d.serviceName)
+	return nil
+}
+
+// namedNetworks replaces the network IDs Swarm stores in the attachments by the network names.
+// Networks that cannot be inspected keep their ID, which then shows as a drift.
+func (d *Adapter) namedNetworks(ctx context.Context, attachments []swarm.NetworkAttachmentConfig) []swarm.NetworkAttachmentConfig {
+	named := make([]swarm.NetworkAttachmentConfig, 0, len(attachments))
+	for _, attachment := range attachments {
+		var resource network.Inspect
+		err := call(ctx, "network_inspect", true, func(ctx context.Context) error {
+			var err error
+			resource, err = d.client.NetworkInspect(ctx, attachment.Target, network.InspectOptions{})
+			return err
+		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to create service: %v", err)
+			log.Printf("Failed to inspect network %s of service %s: %v\n", attachment.Target, d.serviceName, err)
+		} else {
+			attachment.Target = resource.Name
 		}
-
-		log.Printf("Service %s created successfully.\n", serviceName)
-	} else {
-		log.Printf("Service %s already exists.\n", serviceName)
+		named = append(named, attachment)
 	}
-
-	return &Adapter{
-		client:                  cli,
-		serviceName:             serviceName,
-		containerRestartTimeout: timeout,
-	}, nil
+	return named
 }
 
 // initializeSwarm attempts to initialize a Docker Swarm manager
@@ -167,85 +200,174 @@ func uint64Ptr(i uint64) *uint64 {
 	return &i
 }
 
-func (d *Adapter) GetServiceDetails(ctx context.Context) *swarm.Service {
-	services, err := d.client.ServiceList(ctx, types.ServiceListOptions{})
-	handleUnexpectedError(err)
+// GetServiceDetails returns the worker service.
+func (d *Adapter) GetServiceDetails(ctx context.Context) (*swarm.Service, error) {
+	var services []swarm.Service
+	err := call(ctx, "service_list", true, func(ctx context.Context) error {
+		var err error
+		services, err = d.client.ServiceList(ctx, types.ServiceListOptions{
+			Filters: filters.NewArgs(filters.Arg("name", d.serviceName)),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
 
 	for _, service := range services {
 		if service.Spec.Name == d.serviceName {
-			return &service
+			return &service, nil
 		}
 	}
-	handleUnfoundServiceName(d.serviceName)
-	return nil
+	return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, d.serviceName)
+}
+
+func (d *Adapter) Scale(ctx context.Context, replicas uint64) error {
+	return d.updateService(ctx, func(spec *swarm.ServiceSpec) error {
+		if spec.Mode.Replicated == nil {
+			return fmt.Errorf("service %s is not replicated", d.serviceName)
+		}
+		spec.Mode.Replicated.Replicas = &replicas
+		return nil
+	})
 }
 
-func (d *Adapter) ScaleService(ctx context.Context, replicas uint64) error {
-	service := d.GetServiceDetails(ctx)
+// updateService applies a change to the latest version of the worker service spec,
+// starting over when the service was updated concurrently.
+func (d *Adapter) updateService(ctx context.Context, change func(*swarm.ServiceSpec) error) error {
+	for attempt := 1; ; attempt++ {
+		service, err := d.GetServiceDetails(ctx)
+		if err != nil {
+			return err
+		}
+		if err := change(&service.Spec); err != nil {
+			return err
+		}
 
-	service.Spec.Mode.Replicated.Replicas = &replicas
-	_, err := d.client.ServiceUpdate(ctx, service.ID, service.Version, service.Spec, types.ServiceUpdateOptions{})
+		err = call(ctx, "service_update", true, func(ctx context.Context) error {
+			_, err := d.client.ServiceUpdate(ctx, service.ID, service.Version, service.Spec, types.ServiceUpdateOptions{})
+			return err
+		})
+		if !errors.Is(err, ErrVersionConflict) || attempt == retryAttempts {
+			return err
+		}
+		// Someone else updated the service meanwhile, change its latest version
+		log.Printf("Service %s was updated concurrently, reading it again\n", d.serviceName)
+	}
+}
 
-	return err
+func (d *Adapter) Replicas(ctx context.Context) (uint64, error) {
+	service, err := d.GetServiceDetails(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if service.Spec.Mode.Replicated == nil || service.Spec.Mode.Replicated.Replicas == nil {
+		return 0, fmt.Errorf("service %s is not replicated", d.serviceName)
+	}
+	return *service.Spec.Mode.Replicated.Replicas, nil
 }
 
 func (d *Adapter) GetServiceTasks(ctx context.Context) ([]swarm.Task, error) {
-	tasks, err := d.client.TaskList(ctx, types.TaskListOptions{})
-	handleUnexpectedError(err)
+	var tasks []swarm.Task
+	err := call(ctx, "task_list", true, func(ctx context.Context) error {
+		var err error
+		tasks, err = d.client.TaskList(ctx, types.TaskListOptions{
+			Filters: filters.NewArgs(filters.Arg("service", d.serviceName)),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks of service %s: %w", d.serviceName, err)
+	}
+	return tasks, nil
+}
+
+func (d *Adapter) ListWorkers(ctx context.Context) ([]ports.WorkerInfo, error) {
+	tasks, err := d.GetServiceTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	var serviceTasks []swarm.Task
+	workers := make([]ports.WorkerInfo, 0, len(tasks))
 	for _, task := range tasks {
-		if task.ServiceID == d.serviceName {
-			serviceTasks = append(serviceTasks, task)
+		worker := ports.WorkerInfo{
+			ID:    task.ID,
+			Slot:  task.Slot,
+			Node:  task.NodeID,
+			State: string(task.Status.State),
+		}
+		if task.Status.ContainerStatus != nil {
+			worker.ContainerID = task.Status.ContainerStatus.ContainerID
 		}
+		workers = append(workers, worker)
 	}
-	return serviceTasks, nil
+	return workers, nil
 }
 
-func (d *Adapter) RestartTask(ctx context.Context, containerID string) error {
-
-	err := d.client.ContainerStop(ctx, containerID, container.StopOptions{
-		Signal:  "SIGTERM",
-		Timeout: &d.containerRestartTimeout,
+func (d *Adapter) RestartWorker(ctx context.Context, containerID string) error {
+	err := call(ctx, "container_stop", true, func(ctx context.Context) error {
+		return d.client.ContainerStop(ctx, containerID, container.StopOptions{
+			Signal:  "SIGTERM",
+			Timeout: &d.containerRestartTimeout,
+		})
 	})
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to stop container %s: %w", containerID, err)
 	}
 
-	return d.client.ContainerStart(ctx, containerID, container.StartOptions{})
+	err = call(ctx, "container_start", false, func(ctx context.Context) error {
+		return d.client.ContainerStart(ctx, containerID, container.StartOptions{})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start container %s: %w", containerID, err)
+	}
+	return nil
 }
 
-func (d *Adapter) GetContainerLogs(ctx context.Context, containerID string) (string, error) {
-	logReader, err := d.client.ContainerLogs(ctx, containerID, container.LogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-		Timestamps: true,
+func (d *Adapter) GetWorkerLogs(ctx context.Context, containerID string) (string, error) {
+	var logReader io.ReadCloser
+	err := call(ctx, "container_logs", true, func(ctx context.Context) error {
+		var err error
+		logReader, err = d.client.ContainerLogs(ctx, containerID, container.LogsOptions{
+			ShowStdout: true,
+			ShowStderr: true,
+			Timestamps: true,
+		})
+		return err
 	})
-	handleUnexpectedError(err)
-	defer func(logReader io.ReadCloser) {
-		err := logReader.Close()
-		handleUnexpectedError(err)
-	}(logReader)
+	if err != nil {
+		return "", fmt.Errorf("failed to get logs of container %s: %w", containerID, err)
+	}
+	defer logReader.Close()
 
 	logs, err := io.ReadAll(logReader)
-	handleUnexpectedError(err)
+	if err != nil {
+		return "", fmt.Errorf("failed to read logs of container %s: %w", containerID, classify("container_logs", err))
+	}
 	return string(logs), nil
 }
 
-func (d *Adapter) GetContainerIPs(ctx context.Context) ([]string, error) {
+func (d *Adapter) GetWorkerIPs(ctx context.Context) ([]string, error) {
 	var ips []string
 
 	// Fetch tasks for the service
 	tasks, err := d.GetServiceTasks(ctx)
-	handleUnexpectedError(err)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, task := range tasks {
-		if task.Status.State == swarm.TaskStateRunning {
+		if task.Status.State == swarm.TaskStateRunning && task.Status.ContainerStatus != nil {
 			containerID := task.Status.ContainerStatus.ContainerID
 
-			containerDetails, err := d.client.ContainerInspect(ctx, containerID)
+			var containerDetails types.ContainerJSON
+			err := call(ctx, "container_inspect", true, func(ctx context.Context) error {
+				var err error
+				containerDetails, err = d.client.ContainerInspect(ctx, containerID)
+				return err
+			})
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("failed to inspect container %s: %w", containerID, err)
 			}
 
 			networkName := "ingress" // TODO: check if this will be correct upon creating
@@ -255,7 +377,5 @@ func (d *Adapter) GetContainerIPs(ctx context.Context) ([]string, error) {
 		}
 	}
 
-	fmt.Println("IPS:", ips)
-
 	return ips, nil
 }
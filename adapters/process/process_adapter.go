@@ -0,0 +1,284 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+)
+
+var _ ports.WorkerOrchestrator = (*Adapter)(nil)
+
+// urlPlaceholder is replaced by the coordinator URL in the worker arguments.
+const urlPlaceholder = "{url}"
+
+// worker is a worker process started by the adapter.
+type worker struct {
+	id   string
+	slot int
+	cmd  *exec.Cmd
+	logs *logBuffer
+	done chan struct{} // Closed once the process exited
+}
+
+// running reports whether the process has not exited yet.
+func (w *worker) running() bool {
+	select {
+	case <-w.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Adapter implements the WorkerOrchestrator interface by running workers as local processes.
+type Adapter struct {
+	mux            sync.Mutex
+	binaryPath     string
+	args           []string
+	coordinatorURL string
+	stopTimeout    time.Duration
+	workers        []*worker
+	nextID         int
+}
+
+// New creates an Adapter starting the given binary with args, pointed at the coordinator URL.
+// Occurrences of {url} in args are replaced by the URL, which is appended when there are none.
+func New(binaryPath string, args []string, coordinatorURL string, stopTimeout time.Duration) (*Adapter, error) {
+	path, err := exec.LookPath(binaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("worker binary %s not found: %v", binaryPath, err)
+	}
+
+	withURL := make([]string, 0, len(args)+1)
+	placed := false
+	for _, arg := range args {
+		if strings.Contains(arg, urlPlaceholder) {
+			arg = strings.ReplaceAll(arg, urlPlaceholder, coordinatorURL)
+			placed = true
+		}
+		withURL = append(withURL, arg)
+	}
+	if !placed {
+		withURL = append(withURL, coordinatorURL)
+	}
+
+	log.Printf("Workers will run as local processes: %s %s\n", path, strings.Join(withURL, " "))
+	return &Adapter{
+		binaryPath:     path,
+		args:           withURL,
+		coordinatorURL: coordinatorURL,
+		stopTimeout:    stopTimeout,
+	}, nil
+}
+
+func (a *Adapter) Scale(ctx context.Context, replicas uint64) error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	a.reap()
+	for uint64(len(a.workers)) < replicas {
+		a.nextID++
+		w, err := a.start(fmt.Sprintf("local-%d", a.nextID), a.nextID)
+		if err != nil {
+			return err
+		}
+		a.workers = append(a.workers, w)
+	}
+
+	// The most recently started workers are stopped first
+	for uint64(len(a.workers)) > replicas {
+		last := a.workers[len(a.workers)-1]
+		a.workers = a.workers[:len(a.workers)-1]
+		a.stop(last)
+	}
+	return nil
+}
+
+func (a *Adapter) Replicas(ctx context.Context) (uint64, error) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	a.reap()
+	return uint64(len(a.workers)), nil
+}
+
+func (a *Adapter) ListWorkers(ctx context.Context) ([]ports.WorkerInfo, error) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	hostname, _ := os.Hostname()
+	workers := make([]ports.WorkerInfo, 0, len(a.workers))
+	for _, w := range a.workers {
+		state := ports.WorkerRunning
+		if !w.running() {
+			state = "exited"
+		}
+		workers = append(workers, ports.WorkerInfo{
+			ID:          w.id,
+			ContainerID: w.id,
+			Slot:        w.slot,
+			Node:        hostname,
+			State:       state,
+		})
+	}
+	return workers, nil
+}
+
+func (a *Adapter) RestartWorker(ctx context.Context, containerID string) error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	for i, w := range a.workers {
+		if w.id != containerID {
+			continue
+		}
+		a.stop(w)
+		restarted, err := a.start(w.id, w.slot)
+		if err != nil {
+			a.workers = append(a.workers[:i], a.workers[i+1:]...)
+			return err
+		}
+		a.workers[i] = restarted
+		return nil
+	}
+	return fmt.Errorf("worker %s not found", containerID)
+}
+
+func (a *Adapter) GetWorkerLogs(ctx context.Context, containerID string) (string, error) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	for _, w := range a.workers {
+		if w.id == containerID {
+			return w.logs.String(), nil
+		}
+	}
+	return "", fmt.Errorf("worker %s not found", containerID)
+}
+
+func (a *Adapter) GetWorkerIPs(ctx context.Context) ([]string, error) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	var ips []string
+	for _, w := range a.workers {
+		if w.running() {
+			ips = append(ips, "127.0.0.1")
+		}
+	}
+	return ips, nil
+}
+
+// Shutdown stops every worker process, for the coordinator to exit without leaving them behind.
+func (a *Adapter) Shutdown() {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	var wg sync.WaitGroup
+	for _, w := range a.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.stop(w)
+		}()
+	}
+	wg.Wait()
+	a.workers = nil
+	log.Println("Worker processes stopped")
+}
+
+// start launches a worker process with the given ID and slot.
+func (a *Adapter) start(id string, slot int) (*worker, error) {
+	logs := newLogBuffer(64 * 1024)
+	cmd := exec.Command(a.binaryPath, a.args...)
+	cmd.Env = append(os.Environ(), "WORKER_ID="+id, "TASK_SLOT="+strconv.Itoa(slot), "COORDINATOR_URL="+a.coordinatorURL)
+	cmd.Stdout = logs
+	cmd.Stderr = logs
+	cmd.SysProcAttr = sysProcAttr()
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start worker %s: %v", id, err)
+	}
+
+	w := &worker{
+		id:   id,
+		slot: slot,
+		cmd:  cmd,
+		logs: logs,
+		done: make(chan struct{}),
+	}
+	go func() {
+		err := cmd.Wait()
+		log.Printf("Worker process %s exited: %v\n", id, err)
+		close(w.done)
+	}()
+
+	log.Printf("Worker process %s started (pid %d)\n", id, cmd.Process.Pid)
+	return w, nil
+}
+
+// stop interrupts a worker process, killing it if it does not exit in time.
+func (a *Adapter) stop(w *worker) {
+	if !w.running() {
+		return
+	}
+	if err := w.cmd.Process.Signal(os.Interrupt); err != nil {
+		log.Printf("Failed to interrupt worker %s: %v\n", w.id, err)
+	}
+
+	select {
+	case <-w.done:
+	case <-time.After(a.stopTimeout):
+		log.Printf("Worker %s did not stop in time, killing it\n", w.id)
+		if err := w.cmd.Process.Kill(); err != nil {
+			log.Printf("Failed to kill worker %s: %v\n", w.id, err)
+		}
+		<-w.done
+	}
+}
+
+// reap forgets the workers whose process exited on its own.
+func (a *Adapter) reap() {
+	running := a.workers[:0]
+	for _, w := range a.workers {
+		if w.running() {
+			running = append(running, w)
+		}
+	}
+	a.workers = running
+}
+
+// logBuffer keeps the last bytes written by a worker process.
+type logBuffer struct {
+	mux  sync.Mutex
+	data []byte
+	size int
+}
+
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{size: size}
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.data = append(b.data, p...)
+	if len(b.data) > b.size {
+		b.data = b.data[len(b.data)-b.size:]
+	}
+	return len(p), nil
+}
+
+func (b *logBuffer) String() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return string(b.data)
+}
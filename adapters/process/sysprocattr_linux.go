@@ -0,0 +1,8 @@
+package process
+
+import "syscall"
+
+// sysProcAttr has the kernel terminate a worker process when the coordinator dies, even when killed.
+func sysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Pdeathsig: syscall.SIGTERM}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package process
+
+import "syscall"
+
+// sysProcAttr returns no attributes: outside Linux, workers are only stopped by Shutdown.
+func sysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+)
+
+// MemoryStore implements the JobStore interface in memory. Nothing survives a restart.
+type MemoryStore struct {
+	mux         sync.Mutex
+	jobs        map[string]jobs.Job
+	assignments map[string]ports.Assignment
+	results     map[string]string
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs:        make(map[string]jobs.Job),
+		assignments: make(map[string]ports.Assignment),
+		results:     make(map[string]string),
+	}
+}
+
+func (s *MemoryStore) SaveJob(ctx context.Context, job jobs.Job) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *MemoryStore) LoadJobs(ctx context.Context) ([]jobs.Job, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	list := make([]jobs.Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		list = append(list, job)
+	}
+	return list, nil
+}
+
+func (s *MemoryStore) DeleteJobs(ctx context.Context, ids ...string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, id := range ids {
+		delete(s.jobs, id)
+	}
+	return nil
+}
+
+func (s *MemoryStore) SaveAssignment(ctx context.Context, workerID string, assignment ports.Assignment) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.assignments[workerID] = assignment
+	return nil
+}
+
+func (s *MemoryStore) DeleteAssignment(ctx context.Context, workerID string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.assignments, workerID)
+	return nil
+}
+
+func (s *MemoryStore) LoadAssignments(ctx context.Context) (map[string]ports.Assignment, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	assignments := make(map[string]ports.Assignment, len(s.assignments))
+	for workerID, assignment := range s.assignments {
+		assignments[workerID] = assignment
+	}
+	return assignments, nil
+}
+
+func (s *MemoryStore) SaveResult(ctx context.Context, hash, plaintext string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.results[hash] = plaintext
+	return nil
+}
+
+func (s *MemoryStore) GetResult(ctx context.Context, hash string) (string, bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	plaintext, ok := s.results[hash]
+	return plaintext, ok, nil
+}
+
+func (s *MemoryStore) DeleteResult(ctx context.Context, hash string) (bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	_, ok := s.results[hash]
+	delete(s.results, hash)
+	return ok, nil
+}
+
+func (s *MemoryStore) PurgeResults(ctx context.Context) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.results = make(map[string]string)
+	return nil
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+)
+
+// RedisStore implements the JobStore interface on top of Redis hashes.
+type RedisStore struct {
+	client         *redis.Client
+	jobsKey        string // Job ID -> JSON encoded job
+	assignmentsKey string // Worker ID -> JSON encoded assignment
+	resultsKey     string // Hash -> plaintext
+}
+
+// NewRedisStore connects to the Redis server at addr and stores every key under the given prefix.
+func NewRedisStore(ctx context.Context, addr, password, prefix string) (*RedisStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+	})
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis at %s: %v", addr, err)
+	}
+
+	return &RedisStore{
+		client:         client,
+		jobsKey:        prefix + ":jobs",
+		assignmentsKey: prefix + ":assignments",
+		resultsKey:     prefix + ":results",
+	}, nil
+}
+
+func (s *RedisStore) SaveJob(ctx context.Context, job jobs.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job %s: %v", job.ID, err)
+	}
+	return s.client.HSet(ctx, s.jobsKey, job.ID, data).Err()
+}
+
+func (s *RedisStore) LoadJobs(ctx context.Context) ([]jobs.Job, error) {
+	values, err := s.client.HGetAll(ctx, s.jobsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]jobs.Job, 0, len(values))
+	for id, value := range values {
+		var job jobs.Job
+		if err := json.Unmarshal([]byte(value), &job); err != nil {
+			return nil, fmt.Errorf("failed to decode job %s: %v", id, err)
+		}
+		list = append(list, job)
+	}
+	return list, nil
+}
+
+func (s *RedisStore) DeleteJobs(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.client.HDel(ctx, s.jobsKey, ids...).Err()
+}
+
+func (s *RedisStore) SaveAssignment(ctx context.Context, workerID string, assignment ports.Assignment) error {
+	data, err := json.Marshal(assignment)
+	if err != nil {
+		return fmt.Errorf("failed to encode assignment of worker %s: %v", workerID, err)
+	}
+	return s.client.HSet(ctx, s.assignmentsKey, workerID, data).Err()
+}
+
+func (s *RedisStore) DeleteAssignment(ctx context.Context, workerID string) error {
+	return s.client.HDel(ctx, s.assignmentsKey, workerID).Err()
+}
+
+func (s *RedisStore) LoadAssignments(ctx context.Context) (map[string]ports.Assignment, error) {
+	values, err := s.client.HGetAll(ctx, s.assignmentsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := make(map[string]ports.Assignment, len(values))
+	for workerID, value := range values {
+		var assignment ports.Assignment
+		if err := json.Unmarshal([]byte(value), &assignment); err != nil {
+			return nil, fmt.Errorf("failed to decode assignment of worker %s: %v", workerID, err)
+		}
+		assignments[workerID] = assignment
+	}
+	return assignments, nil
+}
+
+func (s *RedisStore) SaveResult(ctx context.Context, hash, plaintext string) error {
+	return s.client.HSet(ctx, s.resultsKey, hash, plaintext).Err()
+}
+
+func (s *RedisStore) GetResult(ctx context.Context, hash string) (string, bool, error) {
+	plaintext, err := s.client.HGet(ctx, s.resultsKey, hash).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return plaintext, true, nil
+}
+
+func (s *RedisStore) DeleteResult(ctx context.Context, hash string) (bool, error) {
+	deleted, err := s.client.HDel(ctx, s.resultsKey, hash).Result()
+	return deleted > 0, err
+}
+
+func (s *RedisStore) PurgeResults(ctx context.Context) error {
+	return s.client.Del(ctx, s.resultsKey).Err()
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/keyspace"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+)
+
+// newTestRedisStore returns a RedisStore backed by an in-process Redis server, along with the server.
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	store, err := NewRedisStore(context.Background(), server.Addr(), "", "test")
+	if err != nil {
+		t.Fatalf("NewRedisStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.client.Close() })
+	return store, server
+}
+
+func TestRedisStoreJobs(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	ctx := context.Background()
+
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	running := jobs.Job{
+		ID:         "running",
+		Submitter:  "api",
+		Hash:       "900150983cd24fb0d6963f7d28e17f72",
+		Algorithm:  "md5",
+		Keyspace:   keyspace.Keyspace{Charset: "abc", MinLength: 1, MaxLength: 3},
+		State:      jobs.Running,
+		Chunks:     4,
+		Dispatched: 2,
+		Completed:  1,
+		CreatedAt:  started,
+		StartedAt:  &started,
+	}
+	wordlist := jobs.Job{ID: "wordlist", Hash: running.Hash, Wordlist: "rockyou", Rules: "best", State: jobs.Queued, CreatedAt: started}
+	for _, job := range []jobs.Job{running, wordlist} {
+		if err := store.SaveJob(ctx, job); err != nil {
+			t.Fatalf("SaveJob(%s) error = %v", job.ID, err)
+		}
+	}
+
+	// Saving again replaces the job
+	running.Completed = 2
+	if err := store.SaveJob(ctx, running); err != nil {
+		t.Fatalf("SaveJob(%s) error = %v", running.ID, err)
+	}
+	if !server.Exists("test:jobs") {
+		t.Errorf("jobs are not stored under the prefix")
+	}
+
+	loaded, err := store.LoadJobs(ctx)
+	if err != nil {
+		t.Fatalf("LoadJobs() error = %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("LoadJobs() returned %d jobs, want 2", len(loaded))
+	}
+	byID := make(map[string]jobs.Job)
+	for _, job := range loaded {
+		byID[job.ID] = job
+	}
+	got := byID["running"]
+	if got.Hash != running.Hash || got.Keyspace != running.Keyspace || got.State != jobs.Running ||
+		got.Completed != 2 || got.Dispatched != 2 || got.StartedAt == nil || !got.StartedAt.Equal(started) {
+		t.Errorf("LoadJobs() job running = %+v, want %+v", got, running)
+	}
+	if got := byID["wordlist"]; got.Wordlist != "rockyou" || got.Rules != "best" || got.State != jobs.Queued {
+		t.Errorf("LoadJobs() job wordlist = %+v, want %+v", got, wordlist)
+	}
+
+	if err := store.DeleteJobs(ctx, "running", "unknown"); err != nil {
+		t.Fatalf("DeleteJobs() error = %v", err)
+	}
+	if err := store.DeleteJobs(ctx); err != nil {
+		t.Fatalf("DeleteJobs() without IDs error = %v", err)
+	}
+	loaded, err = store.LoadJobs(ctx)
+	if err != nil {
+		t.Fatalf("LoadJobs() error = %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].ID != "wordlist" {
+		t.Errorf("LoadJobs() after DeleteJobs() = %+v, want only job wordlist", loaded)
+	}
+}
+
+func TestRedisStoreLoadJobsRejectsCorruptedJobs(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	server.HSet("test:jobs", "broken", "{")
+
+	if _, err := store.LoadJobs(context.Background()); err == nil {
+		t.Errorf("LoadJobs() error = nil, want a decoding error")
+	}
+}
+
+func TestRedisStoreAssignments(t *testing.T) {
+	store, _ := newTestRedisStore(t)
+	ctx := context.Background()
+
+	if err := store.SaveAssignment(ctx, "worker-1", ports.Assignment{JobID: "job", Chunk: 3}); err != nil {
+		t.Fatalf("SaveAssignment() error = %v", err)
+	}
+	if err := store.SaveAssignment(ctx, "worker-2", ports.Assignment{JobID: "job", Chunk: 4}); err != nil {
+		t.Fatalf("SaveAssignment() error = %v", err)
+	}
+	if err := store.DeleteAssignment(ctx, "worker-1"); err != nil {
+		t.Fatalf("DeleteAssignment() error = %v", err)
+	}
+
+	assignments, err := store.LoadAssignments(ctx)
+	if err != nil {
+		t.Fatalf("LoadAssignments() error = %v", err)
+	}
+	want := map[string]ports.Assignment{"worker-2": {JobID: "job", Chunk: 4}}
+	if len(assignments) != len(want) || assignments["worker-2"] != want["worker-2"] {
+		t.Errorf("LoadAssignments() = %v, want %v", assignments, want)
+	}
+}
+
+func TestRedisStoreResults(t *testing.T) {
+	store, _ := newTestRedisStore(t)
+	ctx := context.Background()
+
+	if _, ok, err := store.GetResult(ctx, "unknown"); ok || err != nil {
+		t.Errorf("GetResult(unknown) = %v, %v, want not found", ok, err)
+	}
+	for hash, plaintext := range map[string]string{"md5hash": "abc", "sha1:sha1hash": "with space"} {
+		if err := store.SaveResult(ctx, hash, plaintext); err != nil {
+			t.Fatalf("SaveResult(%s) error = %v", hash, err)
+		}
+	}
+	if plaintext, ok, err := store.GetResult(ctx, "sha1:sha1hash"); !ok || err != nil || plaintext != "with space" {
+		t.Errorf("GetResult(sha1:sha1hash) = %q, %v, %v, want %q", plaintext, ok, err, "with space")
+	}
+
+	if deleted, err := store.DeleteResult(ctx, "md5hash"); !deleted || err != nil {
+		t.Errorf("DeleteResult(md5hash) = %v, %v, want deleted", deleted, err)
+	}
+	if deleted, err := store.DeleteResult(ctx, "md5hash"); deleted || err != nil {
+		t.Errorf("DeleteResult(md5hash) again = %v, %v, want not deleted", deleted, err)
+	}
+	if _, ok, _ := store.GetResult(ctx, "md5hash"); ok {
+		t.Errorf("GetResult(md5hash) found a deleted result")
+	}
+
+	if err := store.PurgeResults(ctx); err != nil {
+		t.Fatalf("PurgeResults() error = %v", err)
+	}
+	if _, ok, _ := store.GetResult(ctx, "sha1:sha1hash"); ok {
+		t.Errorf("GetResult(sha1:sha1hash) found a purged result")
+	}
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/rules"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+)
+
+// FileRulesetStore implements the RulesetStore interface with one file per ruleset in a directory.
+// The files found in the directory on startup are available right away.
+type FileRulesetStore struct {
+	files *uploadDir[ports.Ruleset]
+}
+
+// NewFileRulesetStore creates a FileRulesetStore in dir, creating it if needed, and parses the rulesets already there.
+func NewFileRulesetStore(dir string) (*FileRulesetStore, error) {
+	files, err := newUploadDir("ruleset", dir, ports.ErrRulesetExists, parseRuleset)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRulesetStore{files: files}, nil
+}
+
+// parseRuleset parses a ruleset file to count its rules, rejecting it when a rule is invalid.
+func parseRuleset(name, path string) (ports.Ruleset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ports.Ruleset{}, fmt.Errorf("failed to open ruleset %s: %v", name, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return ports.Ruleset{}, fmt.Errorf("failed to read ruleset %s: %v", name, err)
+	}
+
+	parsed, err := rules.ParseFile(file)
+	if err != nil {
+		return ports.Ruleset{}, fmt.Errorf("invalid ruleset %s: %v", name, err)
+	}
+	if len(parsed) == 0 {
+		return ports.Ruleset{}, fmt.Errorf("ruleset %s has no rules", name)
+	}
+	return ports.Ruleset{Name: name, Rules: len(parsed), Size: info.Size()}, nil
+}
+
+func (s *FileRulesetStore) Save(name string, content io.Reader) (ports.Ruleset, error) {
+	described, err := s.files.save(name, content)
+	if err != nil {
+		return ports.Ruleset{}, err
+	}
+	log.Printf("Ruleset %s saved (%d rules)\n", name, described.Rules)
+	return described, nil
+}
+
+func (s *FileRulesetStore) Get(name string) (ports.Ruleset, bool) {
+	return s.files.get(name)
+}
+
+func (s *FileRulesetStore) List() []ports.Ruleset {
+	return s.files.list()
+}
+
+func (s *FileRulesetStore) Read(name string, w io.Writer) error {
+	if _, ok := s.files.get(name); !ok {
+		return fmt.Errorf("%w: %s", ports.ErrRulesetNotFound, name)
+	}
+	file, err := s.files.open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
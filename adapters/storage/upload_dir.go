@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/names"
+)
+
+// uploadDir keeps files uploaded under a name in a directory, along with a description of each of them.
+// The files found in the directory on startup are available right away.
+type uploadDir[T any] struct {
+	mux      sync.RWMutex
+	kind     string // What the files are, such as "wordlist", for the messages
+	dir      string
+	exists   error // Returned when uploading under a name already taken
+	describe func(name, path string) (T, error)
+	files    map[string]T
+}
+
+// newUploadDir creates an uploadDir in dir, creating it if needed, and describes the files already there.
+// describe checks a file before it is made available, rejecting it with an error.
+func newUploadDir[T any](kind, dir string, exists error, describe func(name, path string) (T, error)) (*uploadDir[T], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s directory %s: %v", kind, dir, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s directory %s: %v", kind, dir, err)
+	}
+
+	u := &uploadDir[T]{
+		kind:     kind,
+		dir:      dir,
+		exists:   exists,
+		describe: describe,
+		files:    make(map[string]T),
+	}
+	for _, entry := range entries {
+		// Interrupted uploads start with a dot and are left out
+		if !entry.Type().IsRegular() || names.Validate(entry.Name()) != nil {
+			continue
+		}
+		described, err := describe(entry.Name(), u.path(entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		u.files[entry.Name()] = described
+	}
+	log.Printf("Loaded %d %ss from %s\n", len(u.files), kind, dir)
+	return u, nil
+}
+
+// save stores a new file read from content.
+// It is written aside and described first, so a failed or rejected upload never shows up.
+func (u *uploadDir[T]) save(name string, content io.Reader) (T, error) {
+	var none T
+	if err := names.Validate(name); err != nil {
+		return none, err
+	}
+	if _, ok := u.get(name); ok {
+		return none, fmt.Errorf("%w: %s", u.exists, name)
+	}
+
+	tmp, err := os.CreateTemp(u.dir, ".upload-"+name+"-*")
+	if err != nil {
+		return none, fmt.Errorf("failed to create %s %s: %v", u.kind, name, err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return none, fmt.Errorf("failed to write %s %s: %w", u.kind, name, err)
+	}
+	described, err := u.describe(name, tmp.Name())
+	if err != nil {
+		return none, err
+	}
+
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	if _, ok := u.files[name]; ok {
+		return none, fmt.Errorf("%w: %s", u.exists, name)
+	}
+	if err := os.Rename(tmp.Name(), u.path(name)); err != nil {
+		return none, fmt.Errorf("failed to save %s %s: %v", u.kind, name, err)
+	}
+	u.files[name] = described
+	return described, nil
+}
+
+// get returns the description of a file.
+func (u *uploadDir[T]) get(name string) (T, bool) {
+	u.mux.RLock()
+	defer u.mux.RUnlock()
+	described, ok := u.files[name]
+	return described, ok
+}
+
+// list returns the descriptions of every file, sorted by name.
+func (u *uploadDir[T]) list() []T {
+	u.mux.RLock()
+	defer u.mux.RUnlock()
+
+	sorted := make([]string, 0, len(u.files))
+	for name := range u.files {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	list := make([]T, 0, len(sorted))
+	for _, name := range sorted {
+		list = append(list, u.files[name])
+	}
+	return list
+}
+
+// open opens a file for reading.
+func (u *uploadDir[T]) open(name string) (*os.File, error) {
+	file, err := os.Open(u.path(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s %s: %v", u.kind, name, err)
+	}
+	return file, nil
+}
+
+// path returns the file of a name.
+func (u *uploadDir[T]) path(name string) string {
+	return filepath.Join(u.dir, name)
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+)
+
+// indexInterval is the number of lines between two offsets remembered to seek into a wordlist.
+const indexInterval = 4096
+
+// indexedWordlist is a wordlist along with the offsets of every indexInterval-th line.
+type indexedWordlist struct {
+	ports.Wordlist
+	offsets []int64
+}
+
+// FileWordlistStore implements the WordlistStore interface with one file per wordlist in a directory.
+// The files found in the directory on startup are available right away.
+type FileWordlistStore struct {
+	files *uploadDir[*indexedWordlist]
+}
+
+// NewFileWordlistStore creates a FileWordlistStore in dir, creating it if needed, and indexes the wordlists already there.
+func NewFileWordlistStore(dir string) (*FileWordlistStore, error) {
+	files, err := newUploadDir("wordlist", dir, ports.ErrWordlistExists, index)
+	if err != nil {
+		return nil, err
+	}
+	return &FileWordlistStore{files: files}, nil
+}
+
+func (s *FileWordlistStore) Save(name string, content io.Reader) (ports.Wordlist, error) {
+	indexed, err := s.files.save(name, content)
+	if err != nil {
+		return ports.Wordlist{}, err
+	}
+	log.Printf("Wordlist %s saved (%d lines)\n", name, indexed.Lines)
+	return indexed.Wordlist, nil
+}
+
+func (s *FileWordlistStore) Get(name string) (ports.Wordlist, bool) {
+	indexed, ok := s.files.get(name)
+	if !ok {
+		return ports.Wordlist{}, false
+	}
+	return indexed.Wordlist, true
+}
+
+func (s *FileWordlistStore) List() []ports.Wordlist {
+	indexed := s.files.list()
+	list := make([]ports.Wordlist, 0, len(indexed))
+	for _, wordlist := range indexed {
+		list = append(list, wordlist.Wordlist)
+	}
+	return list
+}
+
+func (s *FileWordlistStore) ReadLines(name string, first, last uint64, w io.Writer) error {
+	indexed, ok := s.files.get(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ports.ErrWordlistNotFound, name)
+	}
+	if last < first || last >= indexed.Lines {
+		return fmt.Errorf("lines %d to %d are out of wordlist %s, which has %d lines", first, last, name, indexed.Lines)
+	}
+
+	file, err := s.files.open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Seek to the closest indexed line, then skip to the first line
+	line := first / indexInterval * indexInterval
+	if _, err := file.Seek(indexed.offsets[first/indexInterval], io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek into wordlist %s: %v", name, err)
+	}
+	reader := bufio.NewReader(file)
+	for ; line <= last; line++ {
+		text, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// Longer than the buffer, rare enough to allocate
+			var rest []byte
+			rest, err = reader.ReadBytes('\n')
+			text = append(append([]byte(nil), text...), rest...)
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read wordlist %s: %v", name, err)
+		}
+		if line < first {
+			continue
+		}
+		text = bytes.TrimSuffix(text, []byte("\n"))
+		if _, err := w.Write(append(text, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// index counts the lines of a wordlist file and remembers where every indexInterval-th line starts.
+// A last line without a trailing newline counts as a line.
+func index(name, path string) (*indexedWordlist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wordlist %s: %v", name, err)
+	}
+	defer file.Close()
+
+	indexed := &indexedWordlist{Wordlist: ports.Wordlist{Name: name}}
+	reader := bufio.NewReader(file)
+	partial := false // Whether the current line was only partly read
+	for {
+		text, err := reader.ReadSlice('\n')
+		if !partial && len(text) > 0 && indexed.Lines%indexInterval == 0 {
+			indexed.offsets = append(indexed.offsets, indexed.Size)
+		}
+		indexed.Size += int64(len(text))
+
+		switch {
+		case err == bufio.ErrBufferFull:
+			partial = true
+		case err == io.EOF:
+			if len(text) > 0 || partial {
+				indexed.Lines++
+			}
+			return indexed, nil
+		case err != nil:
+			return nil, fmt.Errorf("failed to index wordlist %s: %v", name, err)
+		default:
+			partial = false
+			indexed.Lines++
+		}
+	}
+}
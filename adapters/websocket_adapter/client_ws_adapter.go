@@ -3,6 +3,7 @@ package websocket_adapter
 import (
 	"fmt"
 	"log"
+	"sync"
 
 	"github.com/gorilla/websocket"
 )
@@ -10,6 +11,7 @@ import (
 // ClientWebSocketAdapter implements the ClientCommunicator interface for a single WebSocket client.
 type ClientWebSocketAdapter struct {
 	conn *websocket.Conn // Single WebSocket connection
+	mux  sync.Mutex      // Serializes writes, a WebSocket connection supports a single writer at a time
 }
 
 // NewClientWebSocketAdapter creates a new instance of ClientWebSocketAdapter with a WebSocket connection.
@@ -22,6 +24,8 @@ func NewClientWebSocketAdapter(conn *websocket.Conn) *ClientWebSocketAdapter {
 
 // Send sends a message to the connected client.
 func (adapter *ClientWebSocketAdapter) Send(message []byte) error {
+	adapter.mux.Lock()
+	defer adapter.mux.Unlock()
 	if adapter.conn == nil {
 		return fmt.Errorf("no client connected")
 	}
@@ -48,6 +52,8 @@ func (adapter *ClientWebSocketAdapter) Receive() ([]byte, error) {
 
 // HandleDisconnect gracefully handles the disconnection of the client.
 func (adapter *ClientWebSocketAdapter) HandleDisconnect() error {
+	adapter.mux.Lock()
+	defer adapter.mux.Unlock()
 	if adapter.conn == nil {
 		return fmt.Errorf("no client connected")
 	}
@@ -4,70 +4,132 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/metrics"
 )
 
+// ContainerMessage is a message received from a container.
+type ContainerMessage struct {
+	ContainerID string
+	Message     string
+}
+
+// containerConnection is the WebSocket connection of a container along with its heartbeat state.
+type containerConnection struct {
+	conn     *websocket.Conn
+	writeMux sync.Mutex    // A WebSocket connection supports a single writer at a time
+	done     chan struct{} // Closed when the connection is removed, stops the heartbeat
+}
+
 type ContainerWebSocketAdapter struct {
-	connections     map[string]*websocket.Conn // Maps container IDs to WebSocket connections
-	mux             sync.Mutex
-	SolutionChannel chan string // Channel for forwarding results to SolutionReceiver
+	connections       map[string]*containerConnection // Maps container IDs to WebSocket connections
+	mux               sync.Mutex
+	SolutionChannel   chan ContainerMessage // Channel for forwarding results to SolutionReceiver
+	heartbeatInterval time.Duration         // Delay between two pings sent to a container
+	livenessTimeout   time.Duration         // Silence after which a container is considered dead
 }
 
-func NewContainerWebSocketAdapter() *ContainerWebSocketAdapter {
+func NewContainerWebSocketAdapter(heartbeatInterval, livenessTimeout time.Duration) *ContainerWebSocketAdapter {
 	return &ContainerWebSocketAdapter{
-		connections:     make(map[string]*websocket.Conn),
-		SolutionChannel: make(chan string, 100),
+		connections:       make(map[string]*containerConnection),
+		SolutionChannel:   make(chan ContainerMessage, 100),
+		heartbeatInterval: heartbeatInterval,
+		livenessTimeout:   livenessTimeout,
 	}
 }
 
 func (c *ContainerWebSocketAdapter) AddConnection(containerID string, conn *websocket.Conn) {
 	c.mux.Lock()
 	defer c.mux.Unlock()
-	c.connections[containerID] = conn
+
+	// Any message or pong from the container proves it is alive, reads fail once it stays silent too long
+	conn.SetReadDeadline(time.Now().Add(c.livenessTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(c.livenessTimeout))
+	})
+
+	cc := &containerConnection{
+		conn: conn,
+		done: make(chan struct{}),
+	}
+	c.connections[containerID] = cc
+	go c.heartbeat(containerID, cc)
+	metrics.ConnectedWorkers.Inc()
 	log.Printf("Container %s connected.\n", containerID)
 }
 
+// heartbeat pings a container periodically until its connection is removed.
+func (c *ContainerWebSocketAdapter) heartbeat(containerID string, cc *containerConnection) {
+	ticker := time.NewTicker(c.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cc.done:
+			return
+		case <-ticker.C:
+			err := cc.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.heartbeatInterval))
+			if err != nil {
+				HandleSendError(containerID, err)
+				return
+			}
+		}
+	}
+}
+
 func (c *ContainerWebSocketAdapter) RemoveConnection(containerID string) {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
-	if conn, exists := c.connections[containerID]; exists {
-		conn.Close()
+	if cc, exists := c.connections[containerID]; exists {
+		close(cc.done)
+		cc.conn.Close()
 		delete(c.connections, containerID)
+		metrics.ConnectedWorkers.Dec()
 		log.Printf("Container %s disconnected.\n", containerID)
 	}
 }
 
 func (c *ContainerWebSocketAdapter) SendMessage(containerID string, message []byte) error {
 	c.mux.Lock()
-	conn, ok := c.connections[containerID]
+	cc, ok := c.connections[containerID]
 	c.mux.Unlock()
 	if !ok {
 		return fmt.Errorf("container %s not connected", containerID)
 	}
 
-	err := conn.WriteMessage(websocket.TextMessage, message)
+	cc.writeMux.Lock()
+	err := cc.conn.WriteMessage(websocket.TextMessage, message)
+	cc.writeMux.Unlock()
 	if err != nil {
 		log.Printf("Failed to send message to container %s: %v\n", containerID, err)
+		return err
 	}
-	return err
+	metrics.WorkerMessages.WithLabelValues("out").Inc()
+	return nil
 }
 
 func (c *ContainerWebSocketAdapter) ReceiveMessage(containerID string) error {
-	conn, ok := c.connections[containerID]
+	c.mux.Lock()
+	cc, ok := c.connections[containerID]
+	c.mux.Unlock()
 	if !ok {
 		return fmt.Errorf("container %s not connected", containerID)
 	}
 
-	_, message, err := conn.ReadMessage()
+	// The silence is measured from now, so time spent handing the previous message over is not counted
+	cc.conn.SetReadDeadline(time.Now().Add(c.livenessTimeout))
+	_, message, err := cc.conn.ReadMessage()
 	if err != nil {
 		log.Printf("Error receiving message from container %s: %v\n", containerID, err)
 		return err
 	}
 
 	msg := string(message)
-	c.SolutionChannel <- msg
+	metrics.WorkerMessages.WithLabelValues("in").Inc()
+	c.SolutionChannel <- ContainerMessage{ContainerID: containerID, Message: msg}
 	log.Printf("Message received from container %s: %s\n", containerID, msg)
 	return nil
 }
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+)
+
+// ProtocolVersion is the version of the JSON client protocol spoken by the coordinator.
+const ProtocolVersion = 1
+
+// Types of the messages of the JSON client protocol.
+const (
+	MessageSubmit    = "submit"    // Client: submit the job in "job"
+	MessageCancel    = "cancel"    // Client: cancel the job "jobId"
+	MessageStatus    = "status"    // Client: get the current state of the job "jobId"
+	MessageSubscribe = "subscribe" // Client: receive the result of the job "jobId", submitted by someone else
+	MessageAck       = "ack"       // Coordinator: request accepted, with the current state of the job
+	MessageResult    = "result"    // Coordinator: job finished
+	MessageError     = "error"     // Coordinator: request rejected
+	MessageBusy      = "busy"      // Coordinator: submission refused for now, retry after "retryAfter" seconds
+)
+
+// clientRequest is a message sent by a client speaking the JSON protocol.
+type clientRequest struct {
+	Version int         `json:"v"`
+	Type    string      `json:"type"`
+	ID      string      `json:"id,omitempty"` // Correlation ID chosen by the client, echoed in the replies
+	JobID   string      `json:"jobId,omitempty"`
+	Job     *jobRequest `json:"job,omitempty"`
+}
+
+// clientReply is a message sent to a client speaking the JSON protocol.
+type clientReply struct {
+	Version    int       `json:"v"`
+	Type       string    `json:"type"`
+	ID         string    `json:"id,omitempty"` // Correlation ID of the request answered, or of the request that submitted or subscribed to the job
+	Job        *jobs.Job `json:"job,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	RetryAfter int       `json:"retryAfter,omitempty"` // Seconds to wait before submitting again
+	Candidates []string  `json:"candidates,omitempty"` // Algorithms an ambiguous hash could come from, one must be named
+}
+
+// isJSONMessage reports whether a client message is a JSON object rather than a legacy plain text request.
+func isJSONMessage(message []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(message), []byte("{"))
+}
+
+// encodeReply serializes a reply of the JSON protocol.
+func encodeReply(reply clientReply) ([]byte, error) {
+	reply.Version = ProtocolVersion
+	return json.Marshal(reply)
+}
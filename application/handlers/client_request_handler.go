@@ -1,22 +1,47 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/websocket_adapter"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/hashing"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/keyspace"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ClientRequestHandler struct {
+	clientID        string
 	clientWSAdapter *websocket_adapter.ClientWebSocketAdapter
-	taskDistributor *TaskDistributor
-	resultChannel   chan string // Channel to receive results from SolutionReceiver
+	jobService      *JobService
+	router          *ResultRouter // Delivers the results of the jobs submitted or watched by this client
+	results         <-chan jobs.Job
+	legacy          bool // Plain text protocol, detected from the first message
+
+	mu           sync.Mutex
+	correlations map[string]string // Maps job IDs to the correlation ID of the request that submitted or subscribed to them
+	delivered    map[string]bool   // Job IDs whose result was already sent
 }
 
 // NewClientRequestHandler creates a new ClientRequestHandler instance.
-func NewClientRequestHandler(clientWSAdapter *websocket_adapter.ClientWebSocketAdapter, taskDistributor *TaskDistributor, resultChannel chan string) *ClientRequestHandler {
+func NewClientRequestHandler(clientID string, clientWSAdapter *websocket_adapter.ClientWebSocketAdapter, jobService *JobService, router *ResultRouter) *ClientRequestHandler {
 	return &ClientRequestHandler{
+		clientID:        clientID,
 		clientWSAdapter: clientWSAdapter,
-		taskDistributor: taskDistributor,
-		resultChannel:   resultChannel,
+		jobService:      jobService,
+		router:          router,
+		correlations:    make(map[string]string),
+		delivered:       make(map[string]bool),
 	}
 }
 
@@ -24,15 +49,16 @@ func NewClientRequestHandler(clientWSAdapter *websocket_adapter.ClientWebSocketA
 func (h *ClientRequestHandler) Start() {
 	log.Println("ClientRequestHandler started")
 
-	// Start handling client requests
-	go h.handleClientRequests()
+	// Subscribe before accepting requests so no result of this client is missed
+	h.results = h.router.Subscribe(h.clientID)
 
-	// Start forwarding results to the client
-	go h.forwardResultsToClient()
+	// Start handling client requests, results are forwarded once the protocol is known
+	go h.handleClientRequests()
 }
 
-// handleClientRequests listens for messages from the client and forwards them to the TaskDistributor's TaskChannel.
+// handleClientRequests listens for messages from the client and handles them according to its protocol.
 func (h *ClientRequestHandler) handleClientRequests() {
+	first := true
 	for {
 		// Receive message from the client
 		message, err := h.clientWSAdapter.Receive()
@@ -41,33 +67,276 @@ func (h *ClientRequestHandler) handleClientRequests() {
 			if disconnectErr := h.clientWSAdapter.HandleDisconnect(); disconnectErr != nil {
 				log.Printf("Error handling disconnect: %v\n", disconnectErr)
 			}
+			h.router.Unsubscribe(h.clientID)
 			break
 		}
 
-		hash := string(message)
-		log.Printf("Received hash: %s\n", hash)
+		if first {
+			first = false
+			h.legacy = !isJSONMessage(message)
+			if h.legacy {
+				log.Printf("Client %s speaks the legacy text protocol\n", h.clientID)
+			} else {
+				log.Printf("Client %s speaks the JSON protocol\n", h.clientID)
+			}
+
+			// Start forwarding results to the client
+			go h.forwardResultsToClient()
+		}
 
-		// Forward the hash to the TaskDistributor's TaskChannel
-		select {
-		case h.taskDistributor.TaskChannel <- hash:
-			log.Printf("Hash %s sent to TaskDistributor\n", hash)
-		default:
-			log.Printf("TaskChannel is full, unable to send hash: %s\n", hash)
+		if h.legacy {
+			h.handleLegacyRequest(string(message))
+		} else {
+			h.handleRequest(message)
 		}
 	}
 }
 
-// forwardResultsToClient listens for results from the resultChannel and sends them back to the client.
-func (h *ClientRequestHandler) forwardResultsToClient() {
-	for result := range h.resultChannel {
-		log.Printf("Forwarding result to client: %s\n", result)
+// handleLegacyRequest submits a plain text request of the form "<hash> [<charset> [<min-length> <max-length>]]".
+func (h *ClientRequestHandler) handleLegacyRequest(message string) {
+	ctx, span := h.startReceive("legacy")
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	job, err := parseJob(message)
+	if err == nil {
+		log.Printf("Received hash: %s\n", job.Hash)
+		job.Submitter = h.clientID
+		job, err = h.jobService.Submit(ctx, job)
+		span.SetAttributes(tracing.JobID.String(job.ID))
+	}
+	var busy *BusyError
+	if errors.As(err, &busy) {
+		h.sendBusy(job.Hash, busy)
+		return
+	}
+	if err != nil {
+		log.Printf("Rejected request %q: %v\n", message, err)
+		h.sendError(err)
+	}
+}
+
+// handleRequest answers a message of the JSON protocol with an ack or an error.
+func (h *ClientRequestHandler) handleRequest(message []byte) {
+	var req clientRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		log.Printf("Rejected request %q: %v\n", message, err)
+		h.reply(clientReply{Type: MessageError, Error: fmt.Sprintf("invalid message: %v", err)})
+		return
+	}
+	if req.Version != ProtocolVersion {
+		h.reply(clientReply{Type: MessageError, ID: req.ID, Error: fmt.Sprintf("unsupported protocol version %d, expected %d", req.Version, ProtocolVersion)})
+		return
+	}
+
+	ctx, span := h.startReceive("json")
+	span.SetAttributes(attribute.String("message.type", req.Type), attribute.String("message.id", req.ID))
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	// Held until the ack is sent, so a result is never sent before the ack nor without its correlation ID
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	job, err := h.execute(ctx, req)
+	span.SetAttributes(tracing.JobID.String(job.ID))
+	if err != nil {
+		log.Printf("Rejected %s request %s of client %s: %v\n", req.Type, req.ID, h.clientID, err)
+		reply := clientReply{Type: MessageError, ID: req.ID, Error: err.Error()}
+		var busy *BusyError
+		if errors.As(err, &busy) {
+			reply.Type = MessageBusy
+			reply.RetryAfter = busy.RetryAfterSeconds()
+		}
+		var ambiguous *hashing.AmbiguousError
+		if errors.As(err, &ambiguous) {
+			reply.Candidates = ambiguous.Candidates
+		}
+		if job.ID != "" {
+			reply.Job = &job
+		}
+		h.reply(reply)
+		return
+	}
+	h.reply(clientReply{Type: MessageAck, ID: req.ID, Job: &job})
+
+	// Watching an already finished job delivers its result right away
+	if req.Type == MessageSubscribe && job.State.Terminal() {
+		h.sendResult(job)
+	}
+}
+
+// execute carries out a request of the JSON protocol and returns the job it concerns.
+func (h *ClientRequestHandler) execute(ctx context.Context, req clientRequest) (jobs.Job, error) {
+	switch req.Type {
+	case MessageSubmit:
+		if req.Job == nil {
+			return jobs.Job{}, fmt.Errorf("missing job")
+		}
+		job := req.Job.toJob()
+		job.Submitter = h.clientID
+		job, err := h.jobService.Submit(ctx, job)
+		if job.ID != "" {
+			h.correlations[job.ID] = req.ID
+		}
+		return job, err
+
+	case MessageCancel:
+		if _, ok := h.jobService.Get(req.JobID); !ok {
+			return jobs.Job{}, fmt.Errorf("job %q not found", req.JobID)
+		}
+		return h.jobService.Cancel(req.JobID)
+
+	case MessageStatus:
+		job, ok := h.jobService.Get(req.JobID)
+		if !ok {
+			return jobs.Job{}, fmt.Errorf("job %q not found", req.JobID)
+		}
+		return job, nil
+
+	case MessageSubscribe:
+		if _, ok := h.jobService.Get(req.JobID); !ok {
+			return jobs.Job{}, fmt.Errorf("job %q not found", req.JobID)
+		}
+		h.correlations[req.JobID] = req.ID
+		h.router.Watch(req.JobID, h.clientID)
+
+		// Read again once watched, in case the job finished meanwhile
+		job, _ := h.jobService.Get(req.JobID)
+		return job, nil
+
+	default:
+		return jobs.Job{}, fmt.Errorf("unknown message type %q", req.Type)
+	}
+}
+
+// startReceive starts the span of a message received from the client.
+func (h *ClientRequestHandler) startReceive(protocol string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(context.Background(), "client.receive", trace.WithAttributes(
+		attribute.String("client.id", h.clientID),
+		attribute.String("client.protocol", protocol),
+	))
+}
+
+// reply sends a message of the JSON protocol to the client.
+func (h *ClientRequestHandler) reply(reply clientReply) error {
+	message, err := encodeReply(reply)
+	if err != nil {
+		log.Printf("Error marshalling reply to client: %v\n", err)
+		return err
+	}
+	if err := h.clientWSAdapter.Send(message); err != nil {
+		log.Printf("Error sending reply to client: %v\n", err)
+		return err
+	}
+	return nil
+}
 
-		// Send the result to the client
-		err := h.clientWSAdapter.Send([]byte(result))
+// sendError tells the client its last request was rejected.
+func (h *ClientRequestHandler) sendError(err error) {
+	if sendErr := h.clientWSAdapter.Send([]byte("error " + err.Error())); sendErr != nil {
+		log.Printf("Error sending rejection to client: %v\n", sendErr)
+	}
+}
+
+// sendBusy tells the client its last hash was refused and when to submit it again.
+func (h *ClientRequestHandler) sendBusy(hash string, busy *BusyError) {
+	if err := h.clientWSAdapter.Send([]byte(fmt.Sprintf("busy %s %d", hash, busy.RetryAfterSeconds()))); err != nil {
+		log.Printf("Error sending busy reply to client: %v\n", err)
+	}
+}
+
+// parseJob reads a client request of the form "<hash> [<charset> [<min-length> <max-length>]]".
+// The algorithm is guessed from the length of the hash, 32 hex characters being taken as MD5.
+// The charset is either a preset name (lower, upper, digits, alnum) or the literal characters to use.
+func parseJob(message string) (jobs.Job, error) {
+	fields := strings.Fields(message)
+	job := jobs.Job{Keyspace: keyspace.Default()}
+
+	switch len(fields) {
+	case 4:
+		minLength, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return job, fmt.Errorf("invalid minimum length %q", fields[2])
+		}
+		maxLength, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return job, fmt.Errorf("invalid maximum length %q", fields[3])
+		}
+		job.Keyspace.MinLength = minLength
+		job.Keyspace.MaxLength = maxLength
+		fallthrough
+	case 2:
+		job.Keyspace.Charset = keyspace.ParseCharset(fields[1])
+		fallthrough
+	case 1:
+		job.Hash = fields[0]
+		// Bare hashes as long as MD5 digests are MD5 hashes, as the protocol predates the other algorithms
+		if slices.Contains(hashing.Candidates(job.Hash), hashing.Default) {
+			job.Algorithm = hashing.Default
+		}
+		return job, nil
+	default:
+		return job, fmt.Errorf("expected \"<hash> [<charset> [<min-length> <max-length>]]\", got %d fields", len(fields))
+	}
+}
+
+// forwardResultsToClient listens for the results of the client's jobs and sends them back to the client.
+func (h *ClientRequestHandler) forwardResultsToClient() {
+	for job := range h.results {
+		h.mu.Lock()
+		err := h.sendResult(job)
+		h.mu.Unlock()
 		if err != nil {
-			log.Printf("Error sending result to client: %v\n", err)
 			h.clientWSAdapter.HandleDisconnect()
 			return
 		}
 	}
 }
+
+// sendResult sends the result of a finished job to the client, once. The caller must hold h.mu.
+func (h *ClientRequestHandler) sendResult(job jobs.Job) (err error) {
+	if h.delivered[job.ID] {
+		return nil
+	}
+	h.delivered[job.ID] = true
+
+	span := tracing.StartJobSpan(job.TraceParent, job.ID, "client.send", attribute.String("client.id", h.clientID))
+	defer func() { tracing.End(span, err) }()
+
+	if !h.legacy {
+		log.Printf("Forwarding result of job %s to client %s\n", job.ID, h.clientID)
+		return h.reply(clientReply{Type: MessageResult, ID: h.correlations[job.ID], Job: &job})
+	}
+
+	result := formatResult(job)
+	log.Printf("Forwarding result to client: %s\n", result)
+
+	// Send the result to the client
+	err = h.clientWSAdapter.Send([]byte(result))
+	if err != nil {
+		log.Printf("Error sending result to client: %v\n", err)
+	}
+	return err
+}
+
+// formatResult describes the outcome of a finished job to a client of the plain text protocol.
+// The line the worker sent used to be relayed as is; replies are now built from the job instead,
+// so that solutions found in the cache or by other workers are answered the same way.
+func formatResult(job jobs.Job) string {
+	switch job.State {
+	case jobs.Found:
+		return fmt.Sprintf("found %s %s", job.Hash, job.Result)
+	case jobs.Exhausted:
+		// Names what was searched, depending on the kind of job
+		switch {
+		case job.Wordlist != "":
+			return strings.TrimSpace(fmt.Sprintf("notfound %s wordlist %s %s", job.Hash, job.Wordlist, job.Rules))
+		case job.Mask != "":
+			return strings.TrimSpace(fmt.Sprintf("notfound %s mask %s %s", job.Hash, job.Mask, strings.Join(job.CustomCharsets, " ")))
+		}
+		return fmt.Sprintf("notfound %s %s %d %d", job.Hash, job.Keyspace.Charset, job.Keyspace.MinLength, job.Keyspace.MaxLength)
+	default:
+		return fmt.Sprintf("%s %s %s", job.State, job.Hash, job.Error)
+	}
+}
@@ -2,31 +2,54 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	websocketAdapter "www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/websocket_adapter"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/hashing"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
 )
 
 type ConnectionFactory struct {
 	containerAdapter *websocketAdapter.ContainerWebSocketAdapter
 	taskDistributor  *TaskDistributor
-	resultChannel    chan string
+	jobService       *JobService
+	router           *ResultRouter
+	cache            ports.SolutionCache
+	workers          *WorkerRegistry
+	wordlists        ports.WordlistStore
+	rulesets         ports.RulesetStore
+	maxWordlistSize  int64 // Largest wordlist upload in bytes
 }
 
 // NewConnectionFactory initializes a new ConnectionFactory.
 func NewConnectionFactory(
 	containerAdapter *websocketAdapter.ContainerWebSocketAdapter,
 	taskDistributor *TaskDistributor,
-	resultChannel chan string,
+	jobService *JobService,
+	router *ResultRouter,
+	cache ports.SolutionCache,
+	workers *WorkerRegistry,
+	wordlists ports.WordlistStore,
+	rulesets ports.RulesetStore,
+	maxWordlistSize int64,
 ) *ConnectionFactory {
 	return &ConnectionFactory{
 		containerAdapter: containerAdapter,
 		taskDistributor:  taskDistributor,
-		resultChannel:    resultChannel,
+		jobService:       jobService,
+		router:           router,
+		cache:            cache,
+		workers:          workers,
+		wordlists:        wordlists,
+		rulesets:         rulesets,
+		maxWordlistSize:  maxWordlistSize,
 	}
 }
 
@@ -34,6 +57,26 @@ func NewConnectionFactory(
 func (cf *ConnectionFactory) StartServer(port string) {
 	http.HandleFunc("/ws", cf.HandleConnection)
 	http.HandleFunc("/status", cf.handleContainersInfo)
+	http.Handle("GET /metrics", promhttp.Handler())
+	http.HandleFunc("POST /jobs", cf.handleSubmitJobs)
+	http.HandleFunc("GET /jobs", cf.handleListJobs)
+	http.HandleFunc("GET /jobs/{id}", cf.handleGetJob)
+	wordlists, rulesets := cf.wordlistAPI(), cf.rulesetAPI()
+	http.HandleFunc("GET /wordlists", wordlists.handleList)
+	http.HandleFunc("PUT /wordlists/{name}", wordlists.handleUpload)
+	http.HandleFunc("GET /wordlists/{name}", wordlists.handleGet)
+	http.HandleFunc("GET /wordlists/{name}/lines", cf.handleReadWordlist)
+	http.HandleFunc("GET /rules", rulesets.handleList)
+	http.HandleFunc("PUT /rules/{name}", rulesets.handleUpload)
+	http.HandleFunc("GET /rules/{name}", rulesets.handleGet)
+	http.HandleFunc("GET /rules/{name}/content", cf.handleReadRuleset)
+	http.HandleFunc("DELETE /jobs/{id}", cf.handleCancelJob)
+	http.HandleFunc("GET /workers", cf.handleListWorkers)
+	http.HandleFunc("GET /workers/{id}/logs", cf.handleWorkerLogs)
+	http.HandleFunc("POST /workers/{id}/restart", cf.handleRestartWorker)
+	http.HandleFunc("GET /admin/cache", cf.handleListCache)
+	http.HandleFunc("DELETE /admin/cache", cf.handlePurgeCache)
+	http.HandleFunc("DELETE /admin/cache/{hash}", cf.handleDeleteCachedHash)
 
 	log.Printf("WebSocket and HTTP status server starting on :%s\n", port)
 	err := http.ListenAndServe(":"+port, nil)
@@ -68,13 +111,17 @@ func (cf *ConnectionFactory) HandleConnection(w http.ResponseWriter, r *http.Req
 	msg := strings.TrimSpace(string(message)) // Trim newlines and spaces
 	log.Printf("Connection type identified: %s\n", msg)
 
-	// Route the connection based on type
-	switch msg {
+	// Route the connection based on type, slaves may follow it with their identity
+	fields := strings.Fields(msg)
+	if len(fields) == 0 {
+		fields = []string{""}
+	}
+	switch fields[0] {
 	case "client":
 		cf.handleClientConnection(conn)
 
 	case "slave":
-		cf.handleSlaveConnection(conn)
+		cf.handleSlaveConnection(conn, fields[1:])
 
 	default:
 		log.Printf("Unknown connection type: %s. Closing connection.\n", msg)
@@ -86,22 +133,32 @@ func (cf *ConnectionFactory) HandleConnection(w http.ResponseWriter, r *http.Req
 func (cf *ConnectionFactory) handleClientConnection(conn *websocket.Conn) {
 	log.Println("Initializing client connection")
 
+	clientID := uuid.New().String()
 	clientAdapter := websocketAdapter.NewClientWebSocketAdapter(conn)
-	clientHandler := NewClientRequestHandler(clientAdapter, cf.taskDistributor, cf.resultChannel)
+	clientHandler := NewClientRequestHandler(clientID, clientAdapter, cf.jobService, cf.router)
 
 	go clientHandler.Start()
 }
 
 // handleSlaveConnection initializes a slave connection and listens for messages.
-func (cf *ConnectionFactory) handleSlaveConnection(conn *websocket.Conn) {
+func (cf *ConnectionFactory) handleSlaveConnection(conn *websocket.Conn, handshake []string) {
 	log.Println("Registering slave connection")
 
 	slaveID := uuid.New().String()
+	remoteIP, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		remoteIP = conn.RemoteAddr().String()
+	}
+	cf.workers.Register(slaveID, remoteIP, handshake)
 	cf.containerAdapter.AddConnection(slaveID, conn)
 
 	// Listen for messages from the slave
 	go func() {
-		defer cf.containerAdapter.RemoveConnection(slaveID)
+		defer func() {
+			cf.containerAdapter.RemoveConnection(slaveID)
+			cf.taskDistributor.RemoveWorker(slaveID)
+			cf.workers.Unregister(slaveID)
+		}()
 		for {
 			err := cf.containerAdapter.ReceiveMessage(slaveID)
 			if err != nil {
@@ -125,6 +182,12 @@ func (cf *ConnectionFactory) handleContainersInfo(w http.ResponseWriter, r *http
 		return
 	}
 
+	for i := range *containers {
+		if identity, ok := cf.workers.Get((*containers)[i].ID); ok {
+			(*containers)[i].Worker = &identity
+		}
+	}
+
 	jsonData, err := json.Marshal(containers)
 	if err != nil {
 		log.Printf("Error marshalling containers info: %v\n", err)
@@ -135,3 +198,98 @@ func (cf *ConnectionFactory) handleContainersInfo(w http.ResponseWriter, r *http
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(jsonData)
 }
+
+// handleListWorkers lists the identity of every connected worker.
+func (cf *ConnectionFactory) handleListWorkers(w http.ResponseWriter, r *http.Request) {
+	jsonData, err := json.Marshal(cf.workers.List())
+	if err != nil {
+		log.Printf("Error marshalling workers: %v\n", err)
+		http.Error(w, "Failed to process data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
+
+// handleWorkerLogs returns the logs of the container running a worker.
+func (cf *ConnectionFactory) handleWorkerLogs(w http.ResponseWriter, r *http.Request) {
+	logs, err := cf.workers.GetWorkerLogs(r.Context(), r.PathValue("id"))
+	if err != nil {
+		log.Printf("Error getting worker logs: %v\n", err)
+		http.Error(w, err.Error(), orchestratorErrorStatus(err, http.StatusNotFound))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(logs))
+}
+
+// handleRestartWorker restarts the container running a worker.
+func (cf *ConnectionFactory) handleRestartWorker(w http.ResponseWriter, r *http.Request) {
+	if err := cf.workers.RestartWorker(r.Context(), r.PathValue("id")); err != nil {
+		log.Printf("Error restarting worker: %v\n", err)
+		http.Error(w, err.Error(), orchestratorErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// orchestratorErrorStatus returns 503 Service Unavailable when the orchestrator could not be reached, the given status otherwise.
+func orchestratorErrorStatus(err error, status int) int {
+	if errors.Is(err, ports.ErrOrchestratorUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+	return status
+}
+
+// handleListCache lists every cached solution.
+func (cf *ConnectionFactory) handleListCache(w http.ResponseWriter, r *http.Request) {
+	jsonData, err := json.Marshal(cf.cache.List())
+	if err != nil {
+		log.Printf("Error marshalling cache: %v\n", err)
+		http.Error(w, "Failed to process data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
+
+// handlePurgeCache forgets every cached and saved solution.
+func (cf *ConnectionFactory) handlePurgeCache(w http.ResponseWriter, r *http.Request) {
+	if err := cf.jobService.ForgetAll(r.Context()); err != nil {
+		log.Printf("Failed to purge saved results: %v\n", err)
+		http.Error(w, "Failed to purge saved results", http.StatusInternalServerError)
+		return
+	}
+	log.Println("Solution cache purged")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteCachedHash forgets the cached and saved solution of a single hash.
+// The algorithm is named with ?algo=, or as a prefix of the hash like in the cache, and guessed otherwise.
+func (cf *ConnectionFactory) handleDeleteCachedHash(w http.ResponseWriter, r *http.Request) {
+	hash, algorithm := r.PathValue("hash"), r.URL.Query().Get("algo")
+	if prefix, bare, ok := strings.Cut(hash, ":"); ok && algorithm == "" {
+		hash, algorithm = bare, prefix
+	}
+	hash, algorithm, err := hashing.Identify(hash, algorithm)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	target := hashing.Target(algorithm, hash)
+	found, err := cf.jobService.Forget(r.Context(), target)
+	if err != nil {
+		log.Printf("Failed to delete the saved result of %s: %v\n", target, err)
+		http.Error(w, "Failed to delete saved result", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Hash not cached", http.StatusNotFound)
+		return
+	}
+	log.Printf("Hash %s removed from cache\n", target)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/keyspace"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// apiSubmitter is the submitter of the jobs received over HTTP, whose results are polled rather than pushed.
+const apiSubmitter = "api"
+
+// maxJobsBodySize bounds the size of a submission, batches included.
+const maxJobsBodySize = 1 << 20
+
+// jobRequest is a job submitted over HTTP. Omitted keyspace fields take their default value.
+type jobRequest struct {
+	Hash      string `json:"hash"`
+	Algorithm string `json:"algorithm,omitempty"` // Guessed from the length of the hash when omitted
+	Charset   string `json:"charset,omitempty"`   // Preset name (lower, upper, digits, alnum) or literal characters
+	MinLength int    `json:"minLength,omitempty"`
+	MaxLength int    `json:"maxLength,omitempty"`
+	Wordlist  string `json:"wordlist,omitempty"` // Searches the words of an uploaded wordlist instead of a keyspace
+	Mask      string `json:"mask,omitempty"`     // Searches the words of a mask such as ?u?l?l?d?d instead of a keyspace
+	Rules     string `json:"rules,omitempty"`    // Mangles every word of the wordlist with each rule of an uploaded ruleset
+
+	CustomCharsets []string `json:"customCharsets,omitempty"` // Charsets ?1 to ?4 of the mask
+}
+
+// toJob builds the job described by the request.
+func (req jobRequest) toJob() jobs.Job {
+	job := jobs.Job{
+		Submitter: apiSubmitter,
+		Hash:      req.Hash,
+		Algorithm: req.Algorithm,
+		Keyspace:  keyspace.Default(),
+		Wordlist:  req.Wordlist,
+		Mask:      req.Mask,
+		Rules:     req.Rules,
+
+		CustomCharsets: req.CustomCharsets,
+	}
+	if req.Wordlist != "" || req.Mask != "" {
+		// Dictionary and mask jobs have no keyspace, any keyspace field given is rejected on validation
+		job.Keyspace = keyspace.Keyspace{}
+	}
+	if req.Charset != "" {
+		job.Keyspace.Charset = keyspace.ParseCharset(req.Charset)
+	}
+	if req.MinLength != 0 {
+		job.Keyspace.MinLength = req.MinLength
+	}
+	if req.MaxLength != 0 {
+		job.Keyspace.MaxLength = req.MaxLength
+	}
+	return job
+}
+
+// handleSubmitJobs submits a single job, or a batch of jobs when the body is an array.
+// A batch is rejected as a whole when one of its jobs is invalid.
+func (cf *ConnectionFactory) handleSubmitJobs(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJobsBodySize)).Decode(&body); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("A submission may not be larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Invalid JSON body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	batch := bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+	var requests []jobRequest
+	if batch {
+		if err := json.Unmarshal(body, &requests); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid batch: %v", err), http.StatusBadRequest)
+			return
+		}
+		if len(requests) == 0 {
+			http.Error(w, "Empty batch", http.StatusBadRequest)
+			return
+		}
+	} else {
+		var req jobRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid job: %v", err), http.StatusBadRequest)
+			return
+		}
+		requests = []jobRequest{req}
+	}
+
+	// Every job is validated before any is submitted
+	validated := make([]jobs.Job, 0, len(requests))
+	for i, req := range requests {
+		job, err := cf.jobService.Validate(req.toJob())
+		if err != nil {
+			if batch {
+				err = fmt.Errorf("job %d: %v", i, err)
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		validated = append(validated, job)
+	}
+	ctx, span := tracing.Tracer().Start(r.Context(), "client.receive", trace.WithAttributes(
+		attribute.String("client.protocol", "http"),
+		attribute.Int("jobs.count", len(requests)),
+	))
+	defer span.End()
+
+	// The batch is refused as a whole when busy, jobs of a batch that could not be queued are returned in the failed state
+	submitted, err := cf.jobService.SubmitBatch(ctx, validated)
+	var busy *BusyError
+	if errors.As(err, &busy) && (submitted == nil || !batch) {
+		writeBusy(w, busy)
+		return
+	}
+
+	if batch {
+		writeJSON(w, http.StatusCreated, submitted)
+	} else {
+		writeJSON(w, http.StatusCreated, submitted[0])
+	}
+}
+
+// handleListJobs lists every job, optionally restricted to a state with ?state=.
+func (cf *ConnectionFactory) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	state := jobs.State(r.URL.Query().Get("state"))
+	switch state {
+	case "", jobs.Queued, jobs.Running, jobs.Found, jobs.Exhausted, jobs.Cancelled, jobs.Failed:
+	default:
+		http.Error(w, fmt.Sprintf("Unknown state %q", state), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, cf.jobService.List(state))
+}
+
+// handleGetJob returns the state, progress and result of a job.
+func (cf *ConnectionFactory) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := cf.jobService.Get(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// handleCancelJob cancels an unfinished job.
+func (cf *ConnectionFactory) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, ok := cf.jobService.Get(id); !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	job, err := cf.jobService.Cancel(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// writeBusy refuses a submission with 503 Service Unavailable, telling when to retry.
+func writeBusy(w http.ResponseWriter, busy *BusyError) {
+	w.Header().Set("Retry-After", strconv.Itoa(busy.RetryAfterSeconds()))
+	http.Error(w, busy.Error(), http.StatusServiceUnavailable)
+}
+
+// writeJSON sends a value as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Failed to process data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(jsonData)
+}
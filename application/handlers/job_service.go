@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/hashing"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/metrics"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BusyError is returned when a job is refused because too many jobs are already unfinished.
+type BusyError struct {
+	RetryAfter time.Duration // Delay after which the client may submit again
+}
+
+func (e *BusyError) Error() string {
+	return fmt.Sprintf("too many unfinished jobs, retry in %d seconds", e.RetryAfterSeconds())
+}
+
+// RetryAfterSeconds returns the retry delay rounded up to the second.
+func (e *BusyError) RetryAfterSeconds() int {
+	return int((e.RetryAfter + time.Second - 1) / time.Second)
+}
+
+// JobService submits and cancels jobs on behalf of the WebSocket and HTTP clients.
+type JobService struct {
+	taskDistributor *TaskDistributor
+	registry        *jobs.Registry
+	router          *ResultRouter
+	cache           ports.SolutionCache
+	store           ports.JobStore // Keeps the results of earlier runs, which the cache may have lost
+	maxQueuedJobs   int            // Unfinished jobs above which submissions are refused
+	retryAfter      time.Duration  // Delay suggested to the clients whose submission was refused
+}
+
+// NewJobService creates a new JobService instance.
+func NewJobService(taskDistributor *TaskDistributor, registry *jobs.Registry, router *ResultRouter, cache ports.SolutionCache, store ports.JobStore, maxQueuedJobs int, retryAfter time.Duration) *JobService {
+	return &JobService{
+		taskDistributor: taskDistributor,
+		registry:        registry,
+		router:          router,
+		cache:           cache,
+		store:           store,
+		maxQueuedJobs:   maxQueuedJobs,
+		retryAfter:      retryAfter,
+	}
+}
+
+// Validate reports whether a job can be submitted, and returns it with its hash normalized and its algorithm identified.
+// A *hashing.AmbiguousError is returned when the job names no algorithm and its hash fits several.
+func (s *JobService) Validate(job jobs.Job) (jobs.Job, error) {
+	hash, algorithm, err := hashing.Identify(job.Hash, job.Algorithm)
+	if err == nil {
+		job.Hash, job.Algorithm = hash, algorithm
+		err = s.taskDistributor.Validate(job)
+	}
+	if err != nil {
+		reason := "invalid"
+		var ambiguous *hashing.AmbiguousError
+		if errors.As(err, &ambiguous) {
+			reason = "ambiguous"
+		}
+		metrics.RejectedSubmissions.WithLabelValues(reason).Inc()
+		return job, err
+	}
+	return job, nil
+}
+
+// Submit validates a job, then submits it as SubmitBatch does.
+func (s *JobService) Submit(ctx context.Context, job jobs.Job) (jobs.Job, error) {
+	job, err := s.Validate(job)
+	if err != nil {
+		return job, err
+	}
+	submitted, err := s.SubmitBatch(ctx, []jobs.Job{job})
+	if submitted == nil {
+		return job, err
+	}
+	return submitted[0], err
+}
+
+// SubmitBatch registers validated jobs and hands them to the TaskDistributor, unless their hash was already cracked.
+// The jobs to search are admitted together: when they would leave too many jobs unfinished, none of the jobs
+// is registered and a *BusyError is returned alone. Jobs the TaskDistributor could not take are returned failed,
+// along with a *BusyError. The traces of the jobs start as children of the span in ctx.
+func (s *JobService) SubmitBatch(ctx context.Context, batch []jobs.Job) ([]jobs.Job, error) {
+	// Previously cracked hashes are answered without searching again, even when busy
+	known := make(map[int]string) // Solutions of the jobs already cracked, by position in the batch
+	var searched []jobs.Job
+	var spans []trace.Span
+	for i, job := range batch {
+		if plaintext, ok := s.solved(ctx, job.Target()); ok {
+			known[i] = plaintext
+			continue
+		}
+		span, traceParent := tracing.StartJob(ctx, job.Hash)
+		job.TraceParent = traceParent
+		searched = append(searched, job)
+		spans = append(spans, span)
+	}
+
+	// The room is checked as the jobs are registered, so concurrent submissions never exceed the limit
+	created, ok := s.registry.CreateIfRoom(searched, s.maxQueuedJobs)
+	if !ok {
+		busy := &BusyError{RetryAfter: s.retryAfter}
+		for _, span := range spans {
+			tracing.End(span, busy)
+		}
+		log.Printf("Refused %d jobs: %v\n", len(batch), busy)
+		metrics.RejectedSubmissions.WithLabelValues("busy").Add(float64(len(batch)))
+		return nil, busy
+	}
+	for n, job := range created {
+		tracing.TrackJob(job.ID, spans[n])
+	}
+
+	submitted := make([]jobs.Job, 0, len(batch))
+	var err error
+	for i, job := range batch {
+		if plaintext, ok := known[i]; ok {
+			submitted = append(submitted, s.answer(ctx, job, plaintext))
+			continue
+		}
+		job, created = created[0], created[1:]
+		if sendErr := s.taskDistributor.Submit(job); sendErr != nil {
+			log.Printf("Unable to send job %s: %v\n", job.ID, sendErr)
+			job, _ = s.registry.Fail(job.ID, sendErr.Error())
+			recordFinished(job)
+			metrics.RejectedSubmissions.WithLabelValues("busy").Inc()
+			err = &BusyError{RetryAfter: s.retryAfter}
+		} else {
+			log.Printf("Job %s sent to TaskDistributor\n", job.ID)
+		}
+		submitted = append(submitted, job)
+	}
+	return submitted, err
+}
+
+// answer registers a job whose hash was already cracked as solved.
+func (s *JobService) answer(ctx context.Context, job jobs.Job, plaintext string) jobs.Job {
+	job = s.create(ctx, job)
+	log.Printf("Hash %s found in cache\n", job.Hash)
+	solved, err := s.registry.MarkSolved(job.ID, plaintext)
+	if err != nil {
+		log.Printf("Failed to solve job %s: %v\n", job.ID, err)
+		return job
+	}
+	recordFinished(solved)
+	s.router.Publish(solved)
+	return solved
+}
+
+// solved returns the plaintext of a cracked target from the cache, or else from the results saved by earlier runs.
+func (s *JobService) solved(ctx context.Context, target string) (string, bool) {
+	if plaintext, ok := s.cache.Get(target); ok {
+		return plaintext, true
+	}
+	plaintext, ok, err := s.store.GetResult(ctx, target)
+	if err != nil {
+		log.Printf("Failed to read the saved result of %s: %v\n", target, err)
+		return "", false
+	}
+	if ok {
+		s.cache.Put(target, plaintext)
+	}
+	return plaintext, ok
+}
+
+// Forget drops the solution of a cracked target from the cache and the saved results, and reports whether it was known.
+func (s *JobService) Forget(ctx context.Context, target string) (bool, error) {
+	cached := s.cache.Delete(target)
+	saved, err := s.store.DeleteResult(ctx, target)
+	return cached || saved, err
+}
+
+// ForgetAll drops every solution from the cache and the saved results.
+func (s *JobService) ForgetAll(ctx context.Context) error {
+	s.cache.Purge()
+	return s.store.PurgeResults(ctx)
+}
+
+// create registers a job and starts its trace.
+func (s *JobService) create(ctx context.Context, job jobs.Job) jobs.Job {
+	span, traceParent := tracing.StartJob(ctx, job.Hash)
+	job.TraceParent = traceParent
+	job = s.registry.Create(job)
+	tracing.TrackJob(job.ID, span)
+	return job
+}
+
+// Get returns the job with the given ID.
+func (s *JobService) Get(id string) (jobs.Job, bool) {
+	return s.registry.Get(id)
+}
+
+// List returns every job in the given state, or every job when the state is empty, oldest first.
+func (s *JobService) List(state jobs.State) []jobs.Job {
+	all := s.registry.List()
+	if state == "" {
+		return all
+	}
+
+	filtered := make([]jobs.Job, 0, len(all))
+	for _, job := range all {
+		if job.State == state {
+			filtered = append(filtered, job)
+		}
+	}
+	return filtered
+}
+
+// Cancel stops an unfinished job, dropping its pending chunks and stopping the workers searching it.
+func (s *JobService) Cancel(id string) (jobs.Job, error) {
+	cancelled, err := s.registry.Cancel(id)
+	if err != nil {
+		return cancelled, fmt.Errorf("unable to cancel job: %v", err)
+	}
+	s.taskDistributor.CancelJob(id)
+	recordFinished(cancelled)
+	s.router.Publish(cancelled)
+	return cancelled, nil
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/cache"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/storage"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/keyspace"
+)
+
+// newTestJobService creates a JobService accepting at most maxQueuedJobs unfinished jobs.
+func newTestJobService(t *testing.T, maxQueuedJobs int) *JobService {
+	t.Helper()
+	store := storage.NewMemoryStore()
+	d, _ := newTestDistributor(store)
+	solutions, err := cache.NewLRUCache(10, "")
+	if err != nil {
+		t.Fatalf("NewLRUCache() error = %v", err)
+	}
+	return NewJobService(d, d.registry, NewResultRouter(), solutions, store, maxQueuedJobs, time.Second)
+}
+
+// testJob returns a valid job searching the n-th MD5 hash.
+func testJob(n int) jobs.Job {
+	return jobs.Job{Hash: fmt.Sprintf("%032x", n), Algorithm: "md5", Keyspace: keyspace.Default()}
+}
+
+func TestConcurrentSubmissionsRespectTheLimit(t *testing.T) {
+	const limit, clients = 5, 50
+	service := newTestJobService(t, limit)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted, refused := 0, 0
+	start := make(chan struct{})
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			var err error
+			if i%2 == 0 {
+				_, err = service.Submit(context.Background(), testJob(i))
+			} else {
+				_, err = service.SubmitBatch(context.Background(), []jobs.Job{testJob(i), testJob(clients + i)})
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			var busy *BusyError
+			switch {
+			case err == nil:
+				accepted++
+			case errors.As(err, &busy):
+				refused++
+			default:
+				t.Errorf("Submit() error = %v", err)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	unfinished := len(service.List(jobs.Queued)) + len(service.List(jobs.Running))
+	if unfinished > limit {
+		t.Errorf("%d jobs unfinished, want at most %d", unfinished, limit)
+	}
+	if accepted == 0 || accepted+refused != clients {
+		t.Errorf("%d submissions accepted and %d refused, want some accepted out of %d", accepted, refused, clients)
+	}
+}
+
+func TestSubmitBatchIsRefusedAsAWhole(t *testing.T) {
+	service := newTestJobService(t, 2)
+
+	submitted, err := service.SubmitBatch(context.Background(), []jobs.Job{testJob(1), testJob(2), testJob(3)})
+	var busy *BusyError
+	if !errors.As(err, &busy) || submitted != nil {
+		t.Fatalf("SubmitBatch() = %v, %v, want a *BusyError alone", submitted, err)
+	}
+	if jobs := service.List(""); len(jobs) != 0 {
+		t.Errorf("%d jobs registered after a refused batch, want none", len(jobs))
+	}
+
+	// Hashes already cracked are answered even when busy
+	service.cache.Put(testJob(4).Target(), "abc")
+	if _, err := service.SubmitBatch(context.Background(), []jobs.Job{testJob(5), testJob(6)}); err != nil {
+		t.Fatalf("SubmitBatch() error = %v", err)
+	}
+	solved, err := service.Submit(context.Background(), testJob(4))
+	if err != nil || solved.State != jobs.Found || solved.Result != "abc" {
+		t.Errorf("Submit() of a cracked hash = %+v, %v, want it found", solved, err)
+	}
+}
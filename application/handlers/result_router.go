@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/metrics"
+)
+
+// ResultRouter delivers finished jobs to the client that submitted them and to the clients watching them.
+type ResultRouter struct {
+	mu          sync.Mutex
+	subscribers map[string]*mailbox            // Maps client IDs to their pending results
+	watchers    map[string]map[string]struct{} // Maps job IDs to the clients watching them
+}
+
+// NewResultRouter creates a new ResultRouter instance.
+func NewResultRouter() *ResultRouter {
+	return &ResultRouter{
+		subscribers: make(map[string]*mailbox),
+		watchers:    make(map[string]map[string]struct{}),
+	}
+}
+
+// Subscribe returns the channel on which the jobs submitted or watched by a client are delivered once finished.
+func (r *ResultRouter) Subscribe(clientID string) <-chan jobs.Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	box := newMailbox()
+	r.subscribers[clientID] = box
+	return box.out
+}
+
+// Unsubscribe closes the result channel of a client and stops its watches.
+func (r *ResultRouter) Unsubscribe(clientID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if box, ok := r.subscribers[clientID]; ok {
+		box.close()
+		delete(r.subscribers, clientID)
+	}
+	for jobID, clients := range r.watchers {
+		delete(clients, clientID)
+		if len(clients) == 0 {
+			delete(r.watchers, jobID)
+		}
+	}
+}
+
+// Watch also delivers the result of a job to a client other than its submitter.
+func (r *ResultRouter) Watch(jobID, clientID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.watchers[jobID] == nil {
+		r.watchers[jobID] = make(map[string]struct{})
+	}
+	r.watchers[jobID][clientID] = struct{}{}
+}
+
+// Publish delivers a finished job to its submitter and to the clients watching it.
+func (r *ResultRouter) Publish(job jobs.Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	watchers := r.watchers[job.ID]
+	delete(r.watchers, job.ID)
+
+	if _, watching := watchers[job.Submitter]; !watching {
+		r.deliver(job.Submitter, job)
+	}
+	for clientID := range watchers {
+		r.deliver(clientID, job)
+	}
+}
+
+// deliver queues a finished job for a client, without ever blocking nor dropping it.
+func (r *ResultRouter) deliver(clientID string, job jobs.Job) {
+	box, ok := r.subscribers[clientID]
+	if !ok {
+		if clientID == apiSubmitter {
+			return // Polled over HTTP
+		}
+		log.Printf("Client %s of job %s is gone, result not delivered\n", clientID, job.ID)
+		metrics.DroppedMessages.WithLabelValues("undeliverable_result").Inc()
+		return
+	}
+	box.push(job)
+}
+
+// mailbox queues the results of a client without bound, so a slow client never causes a result to be dropped.
+type mailbox struct {
+	mu      sync.Mutex
+	pending []jobs.Job
+	wake    chan struct{} // Signaled when a result is queued
+	done    chan struct{} // Closed when the client unsubscribes
+	out     chan jobs.Job // Pending results, in order
+}
+
+// newMailbox creates an empty mailbox and starts handing its results out.
+func newMailbox() *mailbox {
+	box := &mailbox{
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+		out:  make(chan jobs.Job),
+	}
+	go box.pump()
+	return box
+}
+
+// push queues a result.
+func (b *mailbox) push(job jobs.Job) {
+	b.mu.Lock()
+	b.pending = append(b.pending, job)
+	b.mu.Unlock()
+
+	// A single pending signal is enough for the pump to drain every result
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close stops handing results out and closes the output channel.
+func (b *mailbox) close() {
+	close(b.done)
+}
+
+// pump hands the queued results out one by one until the mailbox is closed.
+func (b *mailbox) pump() {
+	defer close(b.out)
+	for {
+		b.mu.Lock()
+		if len(b.pending) == 0 {
+			b.mu.Unlock()
+			select {
+			case <-b.wake:
+				continue
+			case <-b.done:
+				return
+			}
+		}
+		job := b.pending[0]
+		b.pending = b.pending[1:]
+		b.mu.Unlock()
+
+		select {
+		case b.out <- job:
+		case <-b.done:
+			return
+		}
+	}
+}
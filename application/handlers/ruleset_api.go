@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+)
+
+// maxRulesetSize bounds the size of an uploaded ruleset, which workers parse in memory.
+const maxRulesetSize = 16 << 20
+
+// rulesetAPI serves the uploads and descriptions of the rulesets.
+func (cf *ConnectionFactory) rulesetAPI() uploadAPI[ports.Ruleset] {
+	return uploadAPI[ports.Ruleset]{
+		kind:   "ruleset",
+		limit:  maxRulesetSize,
+		exists: ports.ErrRulesetExists,
+		save:   cf.rulesets.Save,
+		get:    cf.rulesets.Get,
+		list:   cf.rulesets.List,
+	}
+}
+
+// handleReadRuleset returns a ruleset as uploaded, as plain text.
+// Workers fetch the rules of dictionary jobs here.
+func (cf *ConnectionFactory) handleReadRuleset(w http.ResponseWriter, r *http.Request) {
+	api := cf.rulesetAPI()
+	name := r.PathValue("name")
+	if _, ok := api.get(name); !ok {
+		api.notFound(w, name)
+		return
+	}
+	api.sendText(w, "ruleset "+name, func(w io.Writer) error {
+		return cf.rulesets.Read(name, w)
+	})
+}
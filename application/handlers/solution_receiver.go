@@ -1,39 +1,147 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
 	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/websocket_adapter"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/hashing"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/keyspace"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/metrics"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type SolutionReceiver struct {
 	containerWSAdapter *websocket_adapter.ContainerWebSocketAdapter
-	resultChannel      chan string
+	router             *ResultRouter
 	distributor        *TaskDistributor
+	registry           *jobs.Registry
+	store              ports.JobStore
+	cache              ports.SolutionCache
 }
 
-func NewSolutionReceiver(containerWSAdapter *websocket_adapter.ContainerWebSocketAdapter, resultChannel chan string, distributor *TaskDistributor) *SolutionReceiver {
+func NewSolutionReceiver(containerWSAdapter *websocket_adapter.ContainerWebSocketAdapter, router *ResultRouter, distributor *TaskDistributor, registry *jobs.Registry, store ports.JobStore, cache ports.SolutionCache) *SolutionReceiver {
 	return &SolutionReceiver{
 		containerWSAdapter: containerWSAdapter,
-		resultChannel:      resultChannel,
+		router:             router,
 		distributor:        distributor,
+		registry:           registry,
+		store:              store,
+		cache:              cache,
 	}
 }
 
 func (s *SolutionReceiver) Start() {
 	log.Println("SolutionReceiver started")
-	fmt.Println(s.distributor)
-	for message := range s.containerWSAdapter.SolutionChannel {
-		select {
-		case s.resultChannel <- message:
-			fmt.Println(message)
-			hash, sol := strings.Split(message, " ")[1], strings.Split(message, " ")[2]
-			fmt.Println("Solution received", hash, sol)
-			s.distributor.markWorkerAvailable(s.distributor.GetWorkerFromHash(hash))
-			log.Printf("Forwarded result to resultChannel: %s\n", sol)
-		default:
-			log.Printf("ResultChannel is full. Dropped message: %s\n", message)
+	for received := range s.containerWSAdapter.SolutionChannel {
+		reply, err := parseReply(received.Message)
+		if err != nil {
+			log.Printf("Unexpected message from container %s: %v\n", received.ContainerID, err)
+			metrics.DroppedMessages.WithLabelValues("malformed_worker_message").Inc()
+			continue
+		}
+		if reply.exhausted {
+			s.handleExhausted(received.ContainerID, reply.hash, reply.chunk)
+		} else {
+			s.handleSolution(received.ContainerID, reply.hash, reply.solution)
+		}
+	}
+}
+
+// workerReply is what a worker reports about its chunk.
+type workerReply struct {
+	hash      string
+	exhausted bool           // Whether the chunk was searched without a match
+	chunk     keyspace.Chunk // Range of the exhausted chunk
+	solution  string         // Word matching the hash otherwise
+}
+
+// parseReply reads a message of a worker, "found <hash> <word>" or "exhausted <hash> <begin> <end>".
+// Words of wordlists and masks may contain spaces or be empty, the solution is the rest of the message.
+func parseReply(message string) (workerReply, error) {
+	message = strings.TrimRight(message, "\r\n")
+	kind, _, _ := strings.Cut(message, " ")
+	switch kind {
+	case "exhausted", "notfound":
+		fields := strings.Fields(message)
+		if len(fields) < 4 {
+			// Without its range, the reply could be about a chunk the worker no longer holds
+			return workerReply{}, fmt.Errorf("exhausted reply without range %q", message)
+		}
+		return workerReply{hash: fields[1], exhausted: true, chunk: keyspace.Chunk{Begin: fields[2], End: fields[3]}}, nil
+	default:
+		parts := strings.SplitN(message, " ", 3)
+		if len(parts) < 3 || parts[1] == "" {
+			return workerReply{}, fmt.Errorf("expected \"found <hash> <word>\", got %q", message)
 		}
+		return workerReply{hash: parts[1], solution: parts[2]}, nil
+	}
+}
+
+// handleSolution completes every job targeting a hash once a worker found its solution.
+// The solution is checked against every algorithm, so jobs of another algorithm sharing the hash are left alone.
+func (s *SolutionReceiver) handleSolution(workerID, hash, sol string) {
+	log.Printf("Worker %s sent a solution of hash %s\n", workerID, hash)
+	matched := false
+	for _, name := range hashing.Names() {
+		algorithm, _ := hashing.Lookup(name)
+		if algorithm.Matches(hash, sol) {
+			matched = true
+			s.completeTarget(workerID, hashing.Target(name, hash), sol)
+		}
+	}
+	if !matched {
+		log.Printf("Worker %s sent %q as solution of hash %s, which it is not\n", workerID, sol, hash)
+		metrics.DroppedMessages.WithLabelValues("wrong_solution").Inc()
+	}
+}
+
+// completeTarget completes every job targeting a hash of an algorithm with its solution.
+func (s *SolutionReceiver) completeTarget(workerID, target, sol string) {
+	s.distributor.CompleteHash(target)
+	s.cache.Put(target, sol)
+	if err := s.store.SaveResult(context.Background(), target, sol); err != nil {
+		log.Printf("Failed to persist solution of hash %s: %v\n", target, err)
+	}
+
+	// Every job targeting the hash is solved, whichever client submitted it
+	for _, job := range s.registry.MarkFound(target, sol) {
+		tracing.StartJobSpan(job.TraceParent, job.ID, "solution.receive", attribute.String("worker.id", workerID), attribute.String("job.state", string(job.State))).End()
+		recordFinished(job)
+		s.router.Publish(job)
+		log.Printf("Forwarded result of job %s to client %s\n", job.ID, job.Submitter)
+	}
+}
+
+// handleExhausted frees a worker that searched its whole chunk without a match.
+// The submitter is told once every chunk of the job was searched.
+func (s *SolutionReceiver) handleExhausted(workerID, hash string, chunk keyspace.Chunk) {
+	log.Printf("Worker %s exhausted its chunk %s..%s of hash %s\n", workerID, chunk.Begin, chunk.End, hash)
+	jobID, done := s.distributor.CompleteChunk(workerID, hash, chunk)
+	if !done {
+		return
+	}
+
+	job, err := s.registry.MarkExhausted(jobID)
+	if err != nil {
+		log.Printf("Failed to mark job %s as exhausted: %v\n", jobID, err)
+		return
+	}
+	tracing.StartJobSpan(job.TraceParent, job.ID, "solution.receive", attribute.String("worker.id", workerID), attribute.String("job.state", string(job.State))).End()
+	recordFinished(job)
+	s.router.Publish(job)
+}
+
+// recordFinished records the time taken by a finished job since its submission and ends its trace.
+func recordFinished(job jobs.Job) {
+	tracing.EndJob(job.ID, string(job.State), job.Error)
+	if job.FinishedAt == nil {
+		return
 	}
+	metrics.SolveDuration.WithLabelValues(string(job.State)).Observe(job.FinishedAt.Sub(job.CreatedAt).Seconds())
 }
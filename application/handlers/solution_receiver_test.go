@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"testing"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/keyspace"
+)
+
+func TestParseReply(t *testing.T) {
+	const hash = "d41d8cd98f00b204e9800998ecf8427e"
+	tests := []struct {
+		message string
+		want    workerReply
+		wantErr bool
+	}{
+		{message: "found " + hash + " abc", want: workerReply{hash: hash, solution: "abc"}},
+		{message: "found " + hash + " two words\n", want: workerReply{hash: hash, solution: "two words"}},
+		{message: "found " + hash + " ", want: workerReply{hash: hash, solution: ""}},
+		{message: "found " + hash + "  ", want: workerReply{hash: hash, solution: " "}},
+		{message: "exhausted " + hash + " a zz", want: workerReply{hash: hash, exhausted: true, chunk: keyspace.Chunk{Begin: "a", End: "zz"}}},
+		{message: "notfound " + hash + " 0 99", want: workerReply{hash: hash, exhausted: true, chunk: keyspace.Chunk{Begin: "0", End: "99"}}},
+		{message: "found " + hash, wantErr: true},
+		{message: "found  abc", wantErr: true},
+		{message: "exhausted " + hash, wantErr: true},
+		{message: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseReply(tt.message)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseReply(%q) error = %v, want error %v", tt.message, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseReply(%q) = %+v, want %+v", tt.message, got, tt.want)
+		}
+	}
+}
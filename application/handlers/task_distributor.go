@@ -6,45 +6,148 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"math"
-	"strings"
+	"slices"
+	"sort"
 	"sync"
 	"time"
-	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/docker"
 	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/websocket_adapter"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/hashing"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/keyspace"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/mask"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/scaling"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/metrics"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// partitioner cuts the candidates of a job into chunks, each searched by a single worker.
+type partitioner interface {
+	Count() uint64
+	Chunk(index uint64) keyspace.Chunk
+}
+
+// search tracks the chunks of a hash that still have to be handed out to workers.
+type search struct {
+	jobID       string
+	traceParent string // Trace context of the job, its spans join
+	hash        string
+	algorithm   string
+	charset     string
+	wordlist    string // Wordlist whose line ranges are searched instead of the keyspace, if any
+	mask        string // Mask whose index ranges are searched instead of the keyspace, if any
+	rules       string // Ruleset mangling the words of the wordlist, if any
+	custom      []string
+	partitioner partitioner
+	next        uint64   // Next chunk never handed out
+	completed   uint64   // Chunks searched without finding the solution
+	requeued    []uint64 // Chunks handed back after a failed assignment or by a worker that left, saved with the job
+}
+
+// nextChunk returns the index of the next chunk to hand out.
+func (s *search) nextChunk() (uint64, bool) {
+	if len(s.requeued) > 0 {
+		index := s.requeued[0]
+		s.requeued = s.requeued[1:]
+		return index, true
+	}
+	if s.next < s.partitioner.Count() {
+		s.next++
+		return s.next - 1, true
+	}
+	return 0, false
+}
+
+// message describes a chunk of the search to a worker.
+// Workers assume the default charset and algorithm when none is given.
+func (s *search) message(chunk keyspace.Chunk) string {
+	var message string
+	switch {
+	case s.mask != "":
+		message = fmt.Sprintf("mask %s %s %s %s", s.hash, s.mask, chunk.Begin, chunk.End)
+		for i, charset := range s.custom {
+			message += fmt.Sprintf(" %d=%s", i+1, charset)
+		}
+	case s.wordlist != "":
+		// The worker fetches the lines from the coordinator over HTTP
+		message = fmt.Sprintf("wordlist %s %s %s %s", s.hash, s.wordlist, chunk.Begin, chunk.End)
+		if s.rules != "" {
+			message += " rules=" + s.rules
+		}
+	default:
+		message = fmt.Sprintf("search %s %s %s", s.hash, chunk.Begin, chunk.End)
+		if s.charset != keyspace.DefaultCharset {
+			message += " charset=" + s.charset
+		}
+	}
+	if s.algorithm != "" && s.algorithm != hashing.Default {
+		message += " algo=" + s.algorithm
+	}
+	return message
+}
+
+// remaining returns the number of chunks not yet handed out.
+func (s *search) remaining() int {
+	return int(s.partitioner.Count()-s.next) + len(s.requeued)
+}
+
+// assignment is a chunk of a search currently held by a worker.
+type assignment struct {
+	search *search
+	index  uint64
+	chunk  keyspace.Chunk
+	span   trace.Span // Round-trip of the chunk to the worker
+}
+
 type TaskDistributor struct {
-	TaskChannel        chan string
-	currentQueue       *list.List
+	TaskChannel        chan jobs.Job
+	currentQueue       *list.List // Searches with chunks left to hand out
+	registry           *jobs.Registry
+	store              ports.JobStore
+	wordlists          ports.WordlistStore
+	rulesets           ports.RulesetStore
 	containerWSAdapter *websocket_adapter.ContainerWebSocketAdapter
-	swarmAdapter       *docker.Adapter
+	orchestrator       ports.WorkerOrchestrator
+	orchestratorDown   bool // Whether the last call to the orchestrator failed because it was unreachable
 	mu                 sync.Mutex
-	activeWorkers      map[string]string // Tracks active worker availability "" and unavailability "hash"
-	minReplicas        int
-	maxReplicas        int
-	threshold          int // Tasks per worker before scaling up
+	activeWorkers      map[string]*assignment // Tracks active worker availability nil and unavailability (assigned chunk)
+	policy             scaling.Policy
+	completions        []time.Time // When chunks were searched, within the throughput window
+	chunkSize          uint64      // Candidates per chunk handed to a worker
 }
 
+// throughputWindow is the period over which the throughput of the workers is measured.
+const throughputWindow = time.Minute
+
+// orchestratorTimeout bounds each call made to the orchestrator while scaling, retries included.
+const orchestratorTimeout = 30 * time.Second
+
 // NewDistributor creates a new Distributor instance.
-func NewDistributor(containerWSAdapter *websocket_adapter.ContainerWebSocketAdapter, swarmAdapter *docker.Adapter, minReplicas, maxReplicas, threshold int) *TaskDistributor {
+func NewDistributor(containerWSAdapter *websocket_adapter.ContainerWebSocketAdapter, orchestrator ports.WorkerOrchestrator, registry *jobs.Registry, store ports.JobStore, wordlists ports.WordlistStore, rulesets ports.RulesetStore, policy scaling.Policy, chunkSize uint64) *TaskDistributor {
 	return &TaskDistributor{
-		TaskChannel:        make(chan string, 100),
+		TaskChannel:        make(chan jobs.Job, 100),
 		currentQueue:       list.New(),
+		registry:           registry,
+		store:              store,
+		wordlists:          wordlists,
+		rulesets:           rulesets,
 		containerWSAdapter: containerWSAdapter,
-		swarmAdapter:       swarmAdapter,
-		activeWorkers:      make(map[string]string),
-		minReplicas:        minReplicas,
-		maxReplicas:        maxReplicas,
-		threshold:          threshold,
+		orchestrator:       orchestrator,
+		activeWorkers:      make(map[string]*assignment),
+		policy:             policy,
+		chunkSize:          chunkSize,
 	}
 }
 
 // Start begins distributing tasks and dynamically scaling workers.
 func (d *TaskDistributor) Start(ctx context.Context) {
 	log.Println("Task distributor started")
-	ticker := time.NewTicker(5 * time.Second) // Periodic scaling check
-	defer ticker.Stop()
+	go d.scale(ctx)
+	dispatchTicker := time.NewTicker(time.Second) // Periodic hand out of pending chunks
+	defer dispatchTicker.Stop()
 
 	for {
 		select {
@@ -52,146 +155,590 @@ func (d *TaskDistributor) Start(ctx context.Context) {
 			log.Println("Task distributor shutting down")
 			return
 
-		case hash := <-d.TaskChannel:
-			d.currentQueue.PushBack(hash)
-			workerID, err := d.getAvailableWorker()
-			if err != nil {
-				log.Printf("No available worker for hash: %s. Retrying later.\n", hash)
-				go d.retryTask(hash)
-				continue
+		case job := <-d.TaskChannel:
+			d.enqueue(job)
+			d.dispatch()
+
+		case <-dispatchTicker.C:
+			d.dispatch()
+			d.updateMetrics()
+		}
+	}
+}
+
+// scale periodically applies the scaling policy. It runs aside, so a slow orchestrator never delays the hand out of chunks.
+func (d *TaskDistributor) scale(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Second) // Periodic scaling check
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.manageScaling(ctx)
+		}
+	}
+}
+
+// Validate reports whether a job can be expressed in the worker protocol.
+func (d *TaskDistributor) Validate(job jobs.Job) error {
+	algorithm, err := hashing.Lookup(job.Algorithm)
+	if err != nil {
+		return err
+	}
+	if err := algorithm.Validate(job.Hash); err != nil {
+		return err
+	}
+	if job.Wordlist != "" && job.Mask != "" {
+		return errors.New("a job searches either a wordlist or a mask")
+	}
+	if len(job.CustomCharsets) > 0 && job.Mask == "" {
+		return errors.New("custom charsets only apply to mask jobs")
+	}
+	if job.Rules != "" && job.Wordlist == "" {
+		return errors.New("rules only apply to wordlist jobs")
+	}
+	if (job.Wordlist != "" || job.Mask != "") && job.Keyspace != (keyspace.Keyspace{}) {
+		return errors.New("charset and lengths do not apply to wordlist and mask jobs")
+	}
+	if job.Mask != "" {
+		_, err := maskSize(job)
+		return err
+	}
+	if job.Wordlist != "" {
+		described, ok := d.wordlists.Get(job.Wordlist)
+		if !ok {
+			return fmt.Errorf("%w: %s", ports.ErrWordlistNotFound, job.Wordlist)
+		}
+		if described.Lines == 0 {
+			return fmt.Errorf("wordlist %s is empty", job.Wordlist)
+		}
+		if job.Rules != "" {
+			if _, ok := d.rulesets.Get(job.Rules); !ok {
+				return fmt.Errorf("%w: %s", ports.ErrRulesetNotFound, job.Rules)
 			}
+		}
+		return nil
+	}
+	if err := job.Keyspace.Validate(); err != nil {
+		return fmt.Errorf("invalid keyspace: %v", err)
+	}
+	return nil
+}
 
-			hash = strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(hash, " ", ""), "\n", ""), "\t", "")
+// Submit forwards a registered job to the TaskChannel.
+func (d *TaskDistributor) Submit(job jobs.Job) error {
+	select {
+	case d.TaskChannel <- job:
+		return nil
+	default:
+		return errors.New("task channel is full")
+	}
+}
+
+// Recover reloads the jobs saved in the store and queues the chunks of unfinished ones.
+// Chunks held by a worker when the coordinator stopped are handed out again first, then the requeued ones.
+func (d *TaskDistributor) Recover(ctx context.Context) error {
+	saved, err := d.store.LoadJobs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load jobs: %v", err)
+	}
+	assignments, err := d.store.LoadAssignments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load assignments: %v", err)
+	}
+
+	// Worker sessions do not survive a restart, their chunks go back to the queue
+	inFlight := make(map[string][]uint64)
+	for workerID, a := range assignments {
+		inFlight[a.JobID] = append(inFlight[a.JobID], a.Chunk)
+		if err := d.store.DeleteAssignment(ctx, workerID); err != nil {
+			return fmt.Errorf("failed to delete assignment of worker %s: %v", workerID, err)
+		}
+	}
+
+	sort.Slice(saved, func(i, j int) bool {
+		return saved[i].CreatedAt.Before(saved[j].CreatedAt)
+	})
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-			if err := d.assignTaskToWorker(workerID, hash); err != nil {
-				log.Printf("Failed to assign task to worker %s: %v. Retrying task.\n", workerID, err)
-				go d.retryTask(hash)
+	recovered := 0
+	for _, job := range saved {
+		d.registry.Restore(job)
+		if job.State.Terminal() {
+			continue
+		}
+
+		s, err := d.newSearch(job)
+		if err != nil {
+			log.Printf("Job %s cannot be resumed: %v\n", job.ID, err)
+			d.registry.Fail(job.ID, err.Error())
+			continue
+		}
+		s.next = job.Dispatched
+		s.completed = job.Completed
+		s.requeued = inFlight[job.ID]
+		for _, index := range job.Requeued {
+			// A chunk requeued right before the coordinator stopped may still have been saved as held
+			if !slices.Contains(s.requeued, index) {
+				s.requeued = append(s.requeued, index)
 			}
+		}
+		if s.completed == s.partitioner.Count() {
+			// Stopped right after the last chunk was searched
+			d.registry.MarkExhausted(job.ID)
+			continue
+		}
+		d.currentQueue.PushBack(s)
+		recovered++
+	}
+	log.Printf("Recovered %d unfinished jobs out of %d\n", recovered, len(saved))
+	return nil
+}
 
-		case <-ticker.C:
-			d.manageScaling(ctx)
+// newSearch cuts the keyspace, the wordlist or the mask of a job into chunks.
+func (d *TaskDistributor) newSearch(job jobs.Job) (*search, error) {
+	s := &search{
+		jobID:       job.ID,
+		traceParent: job.TraceParent,
+		hash:        job.Hash,
+		algorithm:   job.Algorithm,
+		charset:     job.Keyspace.Charset,
+		wordlist:    job.Wordlist,
+		mask:        job.Mask,
+		rules:       job.Rules,
+		custom:      job.CustomCharsets,
+	}
+	if job.Mask != "" {
+		size, err := maskSize(job)
+		if err != nil {
+			return nil, err
 		}
+		s.partitioner = keyspace.NewRangePartitioner(size, d.chunkSize)
+		return s, nil
+	}
+	if job.Wordlist == "" {
+		s.partitioner = keyspace.NewPartitioner(job.Keyspace, d.chunkSize)
+		return s, nil
 	}
+
+	described, ok := d.wordlists.Get(job.Wordlist)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ports.ErrWordlistNotFound, job.Wordlist)
+	}
+	// Each line expands into one candidate per rule, chunks keep about chunkSize candidates
+	linesPerChunk := d.chunkSize
+	if job.Rules != "" {
+		ruleset, ok := d.rulesets.Get(job.Rules)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ports.ErrRulesetNotFound, job.Rules)
+		}
+		linesPerChunk = max(1, d.chunkSize/uint64(ruleset.Rules))
+	}
+	s.partitioner = keyspace.NewRangePartitioner(described.Lines, linesPerChunk)
+	return s, nil
 }
 
-// manageScaling scales workers up or down based on the number of tasks in the queue.
-func (d *TaskDistributor) manageScaling(ctx context.Context) {
+// maskSize returns the number of words of the mask of a job.
+func maskSize(job jobs.Job) (uint64, error) {
+	parsed, err := mask.Parse(job.Mask, job.CustomCharsets)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mask: %v", err)
+	}
+	size, err := parsed.Size()
+	if err != nil {
+		return 0, fmt.Errorf("invalid mask: %v", err)
+	}
+	return size, nil
+}
+
+// enqueue cuts the keyspace of a job into chunks and queues them.
+func (d *TaskDistributor) enqueue(job jobs.Job) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	queueSize := d.currentQueue.Len()
-	workerCount := len(d.activeWorkers)
-	desiredReplicas := d.calculateReplicas(queueSize)
+	// The job may have been cancelled while waiting in the TaskChannel
+	if current, ok := d.registry.Get(job.ID); ok && current.State.Terminal() {
+		log.Printf("Job %s is %s, not queued\n", job.ID, current.State)
+		return
+	}
+
+	span := tracing.StartJobSpan(job.TraceParent, job.ID, "distributor.enqueue")
+	defer span.End()
+
+	s, err := d.newSearch(job)
+	if err != nil {
+		log.Printf("Job %s not queued: %v\n", job.ID, err)
+		tracing.End(span, err)
+		if failed, err := d.registry.Fail(job.ID, err.Error()); err == nil {
+			recordFinished(failed)
+		}
+		return
+	}
+	d.currentQueue.PushBack(s)
+	d.registry.SetChunks(job.ID, s.partitioner.Count())
+	span.SetAttributes(attribute.Int64("job.chunks", int64(s.partitioner.Count())))
+	log.Printf("Job %s (hash %s) queued in %d chunks\n", job.ID, job.Hash, s.partitioner.Count())
+}
+
+// dispatch hands pending chunks out to every idle worker.
+func (d *TaskDistributor) dispatch() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	if desiredReplicas > workerCount {
-		log.Printf("Scaling up to %d replicas (current: %d, queue: %d tasks)\n", desiredReplicas, workerCount, queueSize)
-		err := d.swarmAdapter.ScaleService(ctx, uint64(desiredReplicas))
+	d.refreshWorkers()
+	for d.currentQueue.Len() > 0 {
+		workerID, err := d.getAvailableWorker()
 		if err != nil {
 			return
 		}
-		d.refreshWorkers(ctx)
-	} else if desiredReplicas < workerCount && workerCount > d.minReplicas {
-		log.Printf("Scaling down to %d replicas (current: %d, queue: %d tasks)\n", desiredReplicas, workerCount, queueSize)
-		err := d.swarmAdapter.ScaleService(ctx, uint64(desiredReplicas))
-		if err != nil {
+
+		front := d.currentQueue.Front()
+		s := front.Value.(*search)
+		index, ok := s.nextChunk()
+		if !ok {
+			d.currentQueue.Remove(front)
+			continue
+		}
+
+		if err := d.assignTaskToWorker(workerID, s, index); err != nil {
+			log.Printf("Failed to assign chunk %d of hash %s to worker %s: %v. Retrying later.\n", index, s.hash, workerID, err)
+			s.requeued = append(s.requeued, index)
+			d.registry.Requeue(s.jobID, s.next, slices.Clone(s.requeued))
 			return
 		}
-		d.refreshWorkers(ctx)
 	}
 }
 
-// calculateReplicas determines the number of replicas based on the queue size.
-func (d *TaskDistributor) calculateReplicas(queueSize int) int {
-	replicas := int(math.Ceil(float64(queueSize) / float64(d.threshold)))
-	if replicas < d.minReplicas {
-		return d.minReplicas
+// manageScaling asks the scaling policy for the number of replicas and applies it.
+// The orchestrator is called without holding the lock and with a deadline, so a slow or hung one
+// never blocks the workers reporting their chunks.
+func (d *TaskDistributor) manageScaling(ctx context.Context) {
+	callCtx, cancel := context.WithTimeout(ctx, orchestratorTimeout)
+	replicas, err := d.orchestrator.Replicas(callCtx)
+	cancel()
+
+	d.mu.Lock()
+	d.checkOrchestrator(err)
+	if err != nil {
+		d.mu.Unlock()
+		log.Printf("Failed to get current replicas: %v\n", err)
+		return
+	}
+	now := time.Now()
+	inputs := d.scalingInputs(int(replicas), now)
+	d.mu.Unlock()
+
+	decision := d.policy.Decide(inputs, now)
+	log.Printf("Scaling decision (%s policy): %d -> %d replicas, %s (%s)\n", d.policy.Name(), inputs.CurrentReplicas, decision.Replicas, decision.Reason, inputs)
+	metrics.CurrentReplicas.Set(float64(inputs.CurrentReplicas))
+	metrics.DesiredReplicas.Set(float64(decision.Replicas))
+	if decision.Replicas == inputs.CurrentReplicas {
+		return
+	}
+
+	direction := "up"
+	if decision.Replicas < inputs.CurrentReplicas {
+		direction = "down"
+	}
+	callCtx, cancel = context.WithTimeout(ctx, orchestratorTimeout)
+	err = d.orchestrator.Scale(callCtx, uint64(decision.Replicas))
+	cancel()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.checkOrchestrator(err)
+	if err != nil {
+		log.Printf("Failed to scale to %d replicas: %v\n", decision.Replicas, err)
+		metrics.ScalingEvents.WithLabelValues(direction, "error").Inc()
+		return
+	}
+	d.policy.Applied(decision.Replicas, time.Now())
+	metrics.ScalingEvents.WithLabelValues(direction, "ok").Inc()
+	metrics.CurrentReplicas.Set(float64(decision.Replicas))
+	d.refreshWorkers()
+}
+
+// scalingInputs gathers the state the scaling policy decides from. It is called with the lock held.
+func (d *TaskDistributor) scalingInputs(replicas int, now time.Time) scaling.Inputs {
+	inputs := scaling.Inputs{
+		QueueLength:     d.pendingChunks(),
+		CurrentReplicas: replicas,
+		Throughput:      d.throughput(now),
+	}
+	for _, task := range d.activeWorkers {
+		if task == nil {
+			inputs.IdleWorkers++
+		} else {
+			inputs.BusyWorkers++
+		}
+	}
+	return inputs
+}
+
+// checkOrchestrator tracks whether the orchestrator is reachable from the error of the last call to it.
+// While it is not, scaling is skipped and the connected workers keep receiving chunks.
+func (d *TaskDistributor) checkOrchestrator(err error) {
+	down := errors.Is(err, ports.ErrOrchestratorUnavailable)
+	if down && !d.orchestratorDown {
+		log.Printf("Orchestrator unavailable, scaling paused and the %d connected workers kept\n", len(d.activeWorkers))
+	} else if !down && d.orchestratorDown {
+		log.Println("Orchestrator available again, scaling resumed")
 	}
-	if replicas > d.maxReplicas {
-		return d.maxReplicas
+	d.orchestratorDown = down
+	if down {
+		metrics.OrchestratorUp.Set(0)
+	} else {
+		metrics.OrchestratorUp.Set(1)
 	}
-	return replicas
 }
 
-// refreshWorkers updates the active worker list after scaling.
-func (d *TaskDistributor) refreshWorkers(ctx context.Context) {
+// updateMetrics publishes the state of the queue, the workers and the jobs.
+func (d *TaskDistributor) updateMetrics() {
+	d.mu.Lock()
+	metrics.QueuedChunks.Set(float64(d.pendingChunks()))
+	metrics.QueuedSearches.Set(float64(d.currentQueue.Len()))
+	idle, busy := 0, 0
+	for _, task := range d.activeWorkers {
+		if task == nil {
+			idle++
+		} else {
+			busy++
+		}
+	}
+	d.mu.Unlock()
+	metrics.Workers.WithLabelValues("idle").Set(float64(idle))
+	metrics.Workers.WithLabelValues("busy").Set(float64(busy))
+
+	counts := d.registry.CountByState()
+	for _, state := range []jobs.State{jobs.Queued, jobs.Running, jobs.Found, jobs.Exhausted, jobs.Cancelled, jobs.Failed} {
+		metrics.Jobs.WithLabelValues(string(state)).Set(float64(counts[state]))
+	}
+}
+
+// pendingChunks returns the number of chunks waiting for a worker.
+func (d *TaskDistributor) pendingChunks() int {
+	pending := 0
+	for e := d.currentQueue.Front(); e != nil; e = e.Next() {
+		pending += e.Value.(*search).remaining()
+	}
+	return pending
+}
+
+// recordCompletion remembers when a chunk was searched, to measure the throughput.
+func (d *TaskDistributor) recordCompletion(now time.Time) {
+	d.completions = append(d.completions, now)
+}
+
+// throughput returns the chunks searched per second over the last throughputWindow.
+func (d *TaskDistributor) throughput(now time.Time) float64 {
+	recent := d.completions[:0]
+	for _, at := range d.completions {
+		if now.Sub(at) <= throughputWindow {
+			recent = append(recent, at)
+		}
+	}
+	d.completions = recent
+	return float64(len(recent)) / throughputWindow.Seconds()
+}
+
+// refreshWorkers synchronizes the worker list with the open connections, keeping current assignments.
+func (d *TaskDistributor) refreshWorkers() {
 	activeConnections := d.containerWSAdapter.ListConnections()
-	fmt.Printf("Active connections: %d\n", len(activeConnections))
-	d.activeWorkers = make(map[string]string)
+	connected := make(map[string]bool, len(activeConnections))
 	for _, id := range activeConnections {
-		d.activeWorkers[id] = "" // Mark all as available
+		connected[id] = true
+		if _, known := d.activeWorkers[id]; !known {
+			d.activeWorkers[id] = nil // Mark new workers as available
+			log.Printf("Worker %s joined\n", id)
+		}
+	}
+
+	for id := range d.activeWorkers {
+		if !connected[id] {
+			d.dropWorker(id)
+		}
 	}
+}
 
-	log.Printf("Active workers refreshed: %v\n", d.activeWorkers)
+// RemoveWorker forgets a dead or silent worker and puts the chunk it held back in the queue.
+func (d *TaskDistributor) RemoveWorker(workerID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dropWorker(workerID)
+}
+
+// dropWorker forgets a worker, requeueing its chunk in front of the pending ones.
+func (d *TaskDistributor) dropWorker(workerID string) {
+	task, known := d.activeWorkers[workerID]
+	if !known {
+		return
+	}
+	delete(d.activeWorkers, workerID)
+	log.Printf("Worker %s left\n", workerID)
+
+	if task == nil {
+		d.forgetAssignment(workerID)
+		return
+	}
+	tracing.End(task.span, fmt.Errorf("worker %s left", workerID))
+	// The chunk is saved as requeued before the assignment is forgotten, so a restart never loses it
+	s := task.search
+	s.requeued = append([]uint64{task.index}, s.requeued...)
+	d.registry.Requeue(s.jobID, s.next, slices.Clone(s.requeued))
+	d.forgetAssignment(workerID)
+	if !d.isQueued(s) {
+		d.currentQueue.PushFront(s)
+	}
+	log.Printf("Chunk %d of job %s requeued\n", task.index, s.jobID)
+}
+
+// isQueued reports whether a search is still in the queue.
+func (d *TaskDistributor) isQueued(s *search) bool {
+	for e := d.currentQueue.Front(); e != nil; e = e.Next() {
+		if e.Value.(*search) == s {
+			return true
+		}
+	}
+	return false
 }
 
 // getAvailableWorker retrieves an available worker.
 func (d *TaskDistributor) getAvailableWorker() (string, error) {
 	for workerID, task := range d.activeWorkers {
-		if task == "" {
+		if task == nil {
 			return workerID, nil
 		}
 	}
 	return "", errors.New("no available workers")
 }
 
-// assignTaskToWorker assigns a task to a worker.
-func (d *TaskDistributor) assignTaskToWorker(workerID, hash string) error {
-	// Define the 4-character range for brute force
-	begin := "0"
-	end := "ZZZZ"
-
-	// Construct the search message
-	message := fmt.Sprintf("search %s %s %s", hash, begin, end)
-
-	d.activeWorkers[workerID] = hash
+// assignTaskToWorker assigns a chunk of a search to a worker.
+func (d *TaskDistributor) assignTaskToWorker(workerID string, s *search, index uint64) (err error) {
+	chunk := s.partitioner.Chunk(index)
+	attributes := []attribute.KeyValue{
+		attribute.String("worker.id", workerID),
+		attribute.Int64("chunk.index", int64(index)),
+		attribute.String("chunk.begin", chunk.Begin),
+		attribute.String("chunk.end", chunk.End),
+	}
+	span := tracing.StartJobSpan(s.traceParent, s.jobID, "distributor.assign", attributes...)
+	defer func() { tracing.End(span, err) }()
 
 	// Send the message to the worker
-	if err := d.containerWSAdapter.SendMessage(workerID, []byte(message)); err != nil {
-		d.markWorkerAvailable(workerID) // Mark the worker as available again on failure
+	if err := d.containerWSAdapter.SendMessage(workerID, []byte(s.message(chunk))); err != nil {
 		return err
 	}
 
-	log.Printf("Assigned hash %s to worker %s\n", hash, workerID)
+	d.activeWorkers[workerID] = &assignment{
+		search: s,
+		index:  index,
+		chunk:  chunk,
+		span:   tracing.StartJobSpan(s.traceParent, s.jobID, "worker.search", attributes...),
+	}
+	d.registry.MarkDispatched(s.jobID, s.next, slices.Clone(s.requeued))
+	if err := d.store.SaveAssignment(context.Background(), workerID, ports.Assignment{JobID: s.jobID, Chunk: index}); err != nil {
+		log.Printf("Failed to persist assignment of worker %s: %v\n", workerID, err)
+	}
+	log.Printf("Assigned hash %s (%s..%s) to worker %s\n", s.hash, chunk.Begin, chunk.End, workerID)
 	return nil
 }
 
-// retryTask re-queues a task for retrying.
-func (d *TaskDistributor) retryTask(hash string) {
-	time.Sleep(2 * time.Second) // Optional delay before retry
-	d.TaskChannel <- hash
+// CompleteChunk frees a worker that searched its whole chunk of a hash without finding the solution.
+// The chunk is the range echoed by the worker, a late reply about a chunk it no longer holds is ignored.
+// It returns the ID of the job and whether every chunk of the job has now been searched.
+func (d *TaskDistributor) CompleteChunk(workerID, hash string, chunk keyspace.Chunk) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	task := d.activeWorkers[workerID]
+	if task == nil || task.search.hash != hash || task.chunk != chunk {
+		// The worker was stopped or reassigned meanwhile
+		log.Printf("Worker %s holds no chunk %s..%s of hash %s\n", workerID, chunk.Begin, chunk.End, hash)
+		metrics.DroppedMessages.WithLabelValues("stale_worker_report").Inc()
+		return "", false
+	}
+
+	d.activeWorkers[workerID] = nil
+	d.forgetAssignment(workerID)
+	task.span.SetAttributes(attribute.String("chunk.outcome", "exhausted"))
+	task.span.End()
+
+	s := task.search
+	s.completed++
+	d.recordCompletion(time.Now())
+	metrics.ChunksCompleted.Inc()
+	d.registry.MarkCompleted(s.jobID, s.completed)
+	log.Printf("Chunk %d of job %s searched (%d/%d)\n", task.index, s.jobID, s.completed, s.partitioner.Count())
+	return s.jobID, s.completed == s.partitioner.Count()
 }
 
-// markWorkerAvailable marks a worker as available.
-func (d *TaskDistributor) markWorkerAvailable(workerID string) {
+// CompleteHash frees every worker searching a hash and drops its pending chunks, once a solution is found.
+// The hash is identified along with its algorithm, as returned by Job.Target.
+func (d *TaskDistributor) CompleteHash(target string) {
 	d.mu.Lock()
-	fmt.Println("Marking worker as available: ", workerID)
 	defer d.mu.Unlock()
-	d.activeWorkers[workerID] = ""
+
+	d.stopSearches(func(s *search) bool {
+		return hashing.Target(s.algorithm, s.hash) == target
+	})
 }
 
-func (d *TaskDistributor) GetWorkerFromHash(hash string) string {
+// CancelJob frees every worker searching a job and drops its pending chunks.
+func (d *TaskDistributor) CancelJob(jobID string) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	for _, v := range d.activeWorkers {
-		if v == hash {
-			return v
+	d.stopSearches(func(s *search) bool {
+		return s.jobID == jobID
+	})
+}
+
+// stopSearches removes the matching searches from the queue and stops the workers busy on them.
+func (d *TaskDistributor) stopSearches(match func(s *search) bool) {
+	for e := d.currentQueue.Front(); e != nil; {
+		next := e.Next()
+		if match(e.Value.(*search)) {
+			d.currentQueue.Remove(e)
+		}
+		e = next
+	}
+
+	for workerID, task := range d.activeWorkers {
+		if task == nil || !match(task.search) {
+			continue
+		}
+		// Workers still busy on another chunk of the search are told to stop
+		if err := d.containerWSAdapter.SendMessage(workerID, []byte("stop")); err != nil {
+			log.Printf("Failed to stop worker %s: %v\n", workerID, err)
 		}
+		d.activeWorkers[workerID] = nil
+		d.forgetAssignment(workerID)
+		task.span.SetAttributes(attribute.String("chunk.outcome", "stopped"))
+		task.span.End()
+		log.Printf("Marking worker as available: %s\n", workerID)
 	}
-	return ""
 }
 
-func (d *TaskDistributor) RemoveHashFromQueue(hash string) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	delete(d.activeWorkers, hash)
+// forgetAssignment removes the saved assignment of a worker.
+func (d *TaskDistributor) forgetAssignment(workerID string) {
+	if err := d.store.DeleteAssignment(context.Background(), workerID); err != nil {
+		log.Printf("Failed to delete assignment of worker %s: %v\n", workerID, err)
+	}
 }
 
 type ContainerInfo struct {
 	ID      string `json:"id"`
 	GroupID string `json:"groupId"`
 	Status  string `json:"status"`
+	JobID   string `json:"jobId,omitempty"`
 	Hash    string `json:"hash"`
+	Begin   string `json:"begin,omitempty"`
+	End     string `json:"end,omitempty"`
+
+	Worker *WorkerIdentity `json:"worker,omitempty"`
 }
 
 func (d *TaskDistributor) GetContainersInfo() (*[]ContainerInfo, error) {
@@ -200,17 +747,18 @@ func (d *TaskDistributor) GetContainersInfo() (*[]ContainerInfo, error) {
 
 	var containers []ContainerInfo
 
-	for workerID, assignedHash := range d.activeWorkers {
-		status := "inactif"
-		if assignedHash != "" {
-			status = "actif"
-		}
-
+	for workerID, task := range d.activeWorkers {
 		container := ContainerInfo{
 			ID:      workerID,
 			GroupID: "default",
-			Status:  status,
-			Hash:    assignedHash,
+			Status:  "inactif",
+		}
+		if task != nil {
+			container.Status = "actif"
+			container.JobID = task.search.jobID
+			container.Hash = task.search.hash
+			container.Begin = task.chunk.Begin
+			container.End = task.chunk.End
 		}
 
 		containers = append(containers, container)
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/storage"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/websocket_adapter"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/keyspace"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+)
+
+// connectWorker opens a WebSocket connection registered in the adapter under the given ID,
+// and returns the end of it the worker reads its chunks from.
+func connectWorker(t *testing.T, adapter *websocket_adapter.ContainerWebSocketAdapter, workerID string) *websocket.Conn {
+	t.Helper()
+	connected := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade() error = %v", err)
+			return
+		}
+		adapter.AddConnection(workerID, conn)
+		close(connected)
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	<-connected
+	return conn
+}
+
+// readChunk returns the next message sent to a worker.
+func readChunk(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	return string(message)
+}
+
+// newTestDistributor creates a TaskDistributor saving its jobs in store, without orchestrator nor scaling.
+func newTestDistributor(store ports.JobStore) (*TaskDistributor, *websocket_adapter.ContainerWebSocketAdapter) {
+	adapter := websocket_adapter.NewContainerWebSocketAdapter(time.Minute, 2*time.Minute)
+	registry := jobs.NewRegistry(store)
+	return NewDistributor(adapter, nil, registry, store, nil, nil, nil, 4), adapter
+}
+
+func TestRecoverHandsOutChunksRequeuedBeforeRestart(t *testing.T) {
+	store := storage.NewMemoryStore()
+	d, adapter := newTestDistributor(store)
+
+	// 12 words in 3 chunks
+	job := d.registry.Create(jobs.Job{
+		Hash:      "900150983cd24fb0d6963f7d28e17f72",
+		Algorithm: "md5",
+		Keyspace:  keyspace.Keyspace{Charset: "abc", MinLength: 1, MaxLength: 2},
+	})
+	d.enqueue(job)
+	worker := connectWorker(t, adapter, "worker-1")
+	d.dispatch()
+	first := readChunk(t, worker)
+
+	// The worker leaves, its chunk goes back to the queue
+	d.RemoveWorker("worker-1")
+
+	restarted, adapter := newTestDistributor(store)
+	if err := restarted.Recover(context.Background()); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	worker = connectWorker(t, adapter, "worker-2")
+	restarted.dispatch()
+	if got := readChunk(t, worker); got != first {
+		t.Errorf("first chunk after restart = %q, want the requeued %q", got, first)
+	}
+
+	recovered, _ := restarted.registry.Get(job.ID)
+	if recovered.Dispatched != 1 || len(recovered.Requeued) != 0 {
+		t.Errorf("job after dispatch = %d dispatched, %v requeued, want 1 and none", recovered.Dispatched, recovered.Requeued)
+	}
+}
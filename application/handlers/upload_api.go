@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+// uploadAPI serves the files of one kind uploaded to the coordinator under a name, such as wordlists or rulesets.
+type uploadAPI[T any] struct {
+	kind   string // What the files are, such as "wordlist", for the messages
+	limit  int64  // Largest upload in bytes, unlimited when 0
+	exists error  // Returned by save when the name is already taken
+	save   func(name string, content io.Reader) (T, error)
+	get    func(name string) (T, bool)
+	list   func() []T
+}
+
+// handleList lists the uploaded files.
+func (api uploadAPI[T]) handleList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, api.list())
+}
+
+// handleUpload stores the body of the request as a new file.
+func (api uploadAPI[T]) handleUpload(w http.ResponseWriter, r *http.Request) {
+	body := r.Body
+	if api.limit > 0 {
+		body = http.MaxBytesReader(w, r.Body, api.limit)
+	}
+	uploaded, err := api.save(r.PathValue("name"), body)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, api.exists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, &tooLarge):
+		http.Error(w, fmt.Sprintf("A %s may not be larger than %d bytes", api.kind, tooLarge.Limit), http.StatusRequestEntityTooLarge)
+	case err != nil:
+		log.Printf("Failed to save %s: %v\n", api.kind, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		writeJSON(w, http.StatusCreated, uploaded)
+	}
+}
+
+// handleGet describes an uploaded file.
+func (api uploadAPI[T]) handleGet(w http.ResponseWriter, r *http.Request) {
+	described, ok := api.get(r.PathValue("name"))
+	if !ok {
+		api.notFound(w, r.PathValue("name"))
+		return
+	}
+	writeJSON(w, http.StatusOK, described)
+}
+
+// notFound answers that no file was uploaded under a name.
+func (api uploadAPI[T]) notFound(w http.ResponseWriter, name string) {
+	http.Error(w, fmt.Sprintf("No %s named %s", api.kind, name), http.StatusNotFound)
+}
+
+// sendText answers with the plain text written by write, which workers fetch their input from.
+// what describes the content for the logs.
+func (api uploadAPI[T]) sendText(w http.ResponseWriter, what string, write func(io.Writer) error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := write(w); err != nil {
+		// The status is already sent, the worker sees a truncated body
+		log.Printf("Failed to send %s: %v\n", what, err)
+	}
+}
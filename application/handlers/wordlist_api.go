@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+)
+
+// wordlistAPI serves the uploads and descriptions of the wordlists.
+func (cf *ConnectionFactory) wordlistAPI() uploadAPI[ports.Wordlist] {
+	return uploadAPI[ports.Wordlist]{
+		kind:   "wordlist",
+		limit:  cf.maxWordlistSize,
+		exists: ports.ErrWordlistExists,
+		save:   cf.wordlists.Save,
+		get:    cf.wordlists.Get,
+		list:   cf.wordlists.List,
+	}
+}
+
+// handleReadWordlist returns the lines ?first= to ?last= of a wordlist, both included, as plain text.
+// Workers fetch their chunks of dictionary jobs here.
+func (cf *ConnectionFactory) handleReadWordlist(w http.ResponseWriter, r *http.Request) {
+	api := cf.wordlistAPI()
+	name := r.PathValue("name")
+	described, ok := api.get(name)
+	if !ok {
+		api.notFound(w, name)
+		return
+	}
+
+	first, err := strconv.ParseUint(r.URL.Query().Get("first"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid first line", http.StatusBadRequest)
+		return
+	}
+	last, err := strconv.ParseUint(r.URL.Query().Get("last"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid last line", http.StatusBadRequest)
+		return
+	}
+	if last < first || last >= described.Lines {
+		http.Error(w, fmt.Sprintf("Lines %d to %d are out of the %d lines of wordlist %s", first, last, described.Lines, name), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	api.sendText(w, fmt.Sprintf("lines %d to %d of wordlist %s", first, last, name), func(w io.Writer) error {
+		return cf.wordlists.ReadLines(name, first, last, w)
+	})
+}
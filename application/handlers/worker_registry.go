@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+)
+
+// WorkerIdentity joins the WebSocket session of a worker with what the orchestrator knows about it.
+type WorkerIdentity struct {
+	SessionID   string    `json:"sessionId"`
+	ContainerID string    `json:"containerId,omitempty"`
+	Slot        int       `json:"slot,omitempty"`
+	TaskID      string    `json:"taskId,omitempty"`
+	Node        string    `json:"node,omitempty"`
+	IP          string    `json:"ip,omitempty"`
+	ConnectedAt time.Time `json:"connectedAt"`
+}
+
+// WorkerRegistry keeps the identity of every connected worker.
+type WorkerRegistry struct {
+	mu           sync.Mutex
+	workers      map[string]*WorkerIdentity // Maps session IDs to identities
+	orchestrator ports.WorkerOrchestrator
+}
+
+// NewWorkerRegistry creates an empty WorkerRegistry.
+func NewWorkerRegistry(orchestrator ports.WorkerOrchestrator) *WorkerRegistry {
+	return &WorkerRegistry{
+		workers:      make(map[string]*WorkerIdentity),
+		orchestrator: orchestrator,
+	}
+}
+
+// Start periodically joins the connected workers with the orchestrator replicas.
+func (r *WorkerRegistry) Start(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Refresh(ctx)
+		}
+	}
+}
+
+// Register records a worker session from the arguments of its handshake:
+// "slave [<container-id>] [container=<container-id>] [slot=<task-slot>]".
+func (r *WorkerRegistry) Register(sessionID, remoteIP string, handshake []string) {
+	identity := &WorkerIdentity{
+		SessionID:   sessionID,
+		IP:          remoteIP,
+		ConnectedAt: time.Now(),
+	}
+	for _, arg := range handshake {
+		key, value, found := strings.Cut(arg, "=")
+		switch {
+		case !found:
+			identity.ContainerID = arg
+		case key == "container":
+			identity.ContainerID = value
+		case key == "slot":
+			if slot, err := strconv.Atoi(value); err == nil {
+				identity.Slot = slot
+			}
+		default:
+			log.Printf("Ignoring unknown handshake argument %q of worker %s\n", arg, sessionID)
+		}
+	}
+
+	r.mu.Lock()
+	r.workers[sessionID] = identity
+	r.mu.Unlock()
+	log.Printf("Worker %s identified as container %q, slot %d\n", sessionID, identity.ContainerID, identity.Slot)
+
+	go r.Refresh(context.Background())
+}
+
+// Unregister forgets a worker session.
+func (r *WorkerRegistry) Unregister(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.workers, sessionID)
+}
+
+// Get returns the identity of a worker session.
+func (r *WorkerRegistry) Get(sessionID string) (WorkerIdentity, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	identity, ok := r.workers[sessionID]
+	if !ok {
+		return WorkerIdentity{}, false
+	}
+	return *identity, true
+}
+
+// List returns the identity of every connected worker, oldest first.
+func (r *WorkerRegistry) List() []WorkerIdentity {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]WorkerIdentity, 0, len(r.workers))
+	for _, identity := range r.workers {
+		list = append(list, *identity)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ConnectedAt.Before(list[j].ConnectedAt)
+	})
+	return list
+}
+
+// Refresh completes the identities with the task, node and full container ID known to the orchestrator.
+func (r *WorkerRegistry) Refresh(ctx context.Context) {
+	replicas, err := r.orchestrator.ListWorkers(ctx)
+	if err != nil {
+		log.Printf("Failed to list workers: %v\n", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, identity := range r.workers {
+		for _, replica := range replicas {
+			if !matches(identity, replica) {
+				continue
+			}
+			identity.ContainerID = replica.ContainerID
+			identity.Slot = replica.Slot
+			identity.TaskID = replica.ID
+			identity.Node = replica.Node
+			break
+		}
+	}
+}
+
+// shortContainerID is the length of the short container IDs Docker uses as hostnames.
+const shortContainerID = 12
+
+// matches reports whether a worker session runs in an orchestrator replica.
+// Containers usually report their hostname, which is the short form of their full ID.
+func matches(identity *WorkerIdentity, replica ports.WorkerInfo) bool {
+	if identity.ContainerID != "" && replica.ContainerID != "" {
+		if identity.ContainerID == replica.ContainerID {
+			return true
+		}
+		short := len(identity.ContainerID) >= shortContainerID && strings.Trim(identity.ContainerID, "0123456789abcdef") == ""
+		return short && strings.HasPrefix(replica.ContainerID, identity.ContainerID)
+	}
+	// Swarm keeps the tasks that were shut down in their slot, only the running one can hold the session
+	return replica.State == ports.WorkerRunning && identity.Slot != 0 && identity.Slot == replica.Slot
+}
+
+// RestartWorker restarts the container running a worker session.
+func (r *WorkerRegistry) RestartWorker(ctx context.Context, sessionID string) error {
+	containerID, err := r.containerOf(sessionID)
+	if err != nil {
+		return err
+	}
+	log.Printf("Restarting container %s of worker %s\n", containerID, sessionID)
+	return r.orchestrator.RestartWorker(ctx, containerID)
+}
+
+// GetWorkerLogs returns the logs of the container running a worker session.
+func (r *WorkerRegistry) GetWorkerLogs(ctx context.Context, sessionID string) (string, error) {
+	containerID, err := r.containerOf(sessionID)
+	if err != nil {
+		return "", err
+	}
+	return r.orchestrator.GetWorkerLogs(ctx, containerID)
+}
+
+// containerOf returns the container ID of a worker session.
+func (r *WorkerRegistry) containerOf(sessionID string) (string, error) {
+	identity, ok := r.Get(sessionID)
+	if !ok {
+		return "", fmt.Errorf("worker %s not connected", sessionID)
+	}
+	if identity.ContainerID == "" {
+		return "", fmt.Errorf("worker %s did not identify its container", sessionID)
+	}
+	return identity.ContainerID, nil
+}
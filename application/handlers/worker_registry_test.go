@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"testing"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+)
+
+func TestMatches(t *testing.T) {
+	const fullID = "4f2c1e9a7b3d8c6e5f0a1b2c3d4e5f60718293a4b5c6d7e8f9012345678abcde"
+	tests := []struct {
+		name     string
+		identity WorkerIdentity
+		replica  ports.WorkerInfo
+		want     bool
+	}{
+		{"same ID", WorkerIdentity{ContainerID: "local-1"}, ports.WorkerInfo{ContainerID: "local-1"}, true},
+		{"other process", WorkerIdentity{ContainerID: "local-1"}, ports.WorkerInfo{ContainerID: "local-10"}, false},
+		{"hostname", WorkerIdentity{ContainerID: fullID[:12]}, ports.WorkerInfo{ContainerID: fullID}, true},
+		{"short prefix", WorkerIdentity{ContainerID: fullID[:4]}, ports.WorkerInfo{ContainerID: fullID}, false},
+		{"other container", WorkerIdentity{ContainerID: "0123456789ab"}, ports.WorkerInfo{ContainerID: fullID}, false},
+		{"running slot", WorkerIdentity{Slot: 2}, ports.WorkerInfo{ContainerID: fullID, Slot: 2, State: ports.WorkerRunning}, true},
+		{"shut down slot", WorkerIdentity{Slot: 2}, ports.WorkerInfo{ContainerID: fullID, Slot: 2, State: "shutdown"}, false},
+		{"other slot", WorkerIdentity{Slot: 2}, ports.WorkerInfo{Slot: 3, State: ports.WorkerRunning}, false},
+		{"no slot", WorkerIdentity{}, ports.WorkerInfo{State: ports.WorkerRunning}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matches(&tt.identity, tt.replica); got != tt.want {
+				t.Errorf("matches(%+v, %+v) = %v, want %v", tt.identity, tt.replica, got, tt.want)
+			}
+		})
+	}
+}
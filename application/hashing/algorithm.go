@@ -0,0 +1,111 @@
+// Package hashing lists the hash algorithms the coordinator accepts and the workers crack.
+package hashing
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// Default is the algorithm of the jobs that name none, the only one known to servuc/hash_extractor.
+const Default = "md5"
+
+// Algorithm is a hash function jobs can target.
+type Algorithm struct {
+	Name   string
+	Size   int                                  // Length of the digests in bytes
+	new    func() hash.Hash                     // Creates the underlying hash function
+	encode func(word []byte, buf []byte) []byte // Turns a word into the bytes hashed, nil to hash it as is
+}
+
+// algorithms is the registry of supported algorithms, in the order they are listed.
+var algorithms = []Algorithm{
+	{Name: "md5", Size: md5.Size, new: md5.New},
+	{Name: "sha1", Size: sha1.Size, new: sha1.New},
+	{Name: "sha256", Size: sha256.Size, new: sha256.New},
+	{Name: "sha512", Size: sha512.Size, new: sha512.New},
+	{Name: "ntlm", Size: md4.Size, new: md4.New, encode: utf16LE},
+}
+
+// Lookup returns the algorithm with the given name, the default one when the name is empty.
+func Lookup(name string) (Algorithm, error) {
+	if name == "" {
+		name = Default
+	}
+	for _, algorithm := range algorithms {
+		if algorithm.Name == name {
+			return algorithm, nil
+		}
+	}
+	return Algorithm{}, fmt.Errorf("unknown algorithm %q, expected one of %s", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the names of the supported algorithms.
+func Names() []string {
+	names := make([]string, len(algorithms))
+	for i, algorithm := range algorithms {
+		names[i] = algorithm.Name
+	}
+	return names
+}
+
+// Validate reports whether a hash is a hexadecimal digest of the algorithm.
+func (a Algorithm) Validate(hash string) error {
+	digest, err := hex.DecodeString(hash)
+	if err != nil {
+		return fmt.Errorf("hash %q is not hexadecimal", hash)
+	}
+	if len(digest) != a.Size {
+		return fmt.Errorf("hash %q has %d hex characters, %s digests have %d", hash, len(hash), a.Name, 2*a.Size)
+	}
+	return nil
+}
+
+// Hasher returns a function hashing words with the algorithm.
+// The returned digest is only valid until the next call, and the function is not safe for concurrent use.
+func (a Algorithm) Hasher() func(word []byte) []byte {
+	h := a.new()
+	var encoded, digest []byte
+	return func(word []byte) []byte {
+		if a.encode != nil {
+			encoded = a.encode(word, encoded[:0])
+			word = encoded
+		}
+		h.Reset()
+		h.Write(word)
+		digest = h.Sum(digest[:0])
+		return digest
+	}
+}
+
+// Matches reports whether a word hashes to the hexadecimal hash.
+func (a Algorithm) Matches(hash, word string) bool {
+	digest, err := hex.DecodeString(hash)
+	return err == nil && bytes.Equal(a.Hasher()([]byte(word)), digest)
+}
+
+// Target identifies a hash along with its algorithm, so equal digests of different algorithms are told apart.
+// MD5 hashes are identified by the hash alone, as they were before other algorithms were supported.
+func Target(algorithm, hash string) string {
+	if algorithm == "" || algorithm == Default {
+		return hash
+	}
+	return algorithm + ":" + hash
+}
+
+// utf16LE encodes a word to UTF-16 little-endian, as NTLM hashes passwords.
+func utf16LE(word []byte, buf []byte) []byte {
+	for _, unit := range utf16.Encode([]rune(string(word))) {
+		buf = append(buf, byte(unit), byte(unit>>8))
+	}
+	return buf
+}
@@ -0,0 +1,71 @@
+package hashing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AmbiguousError is returned when a hash could come from several algorithms and none was named.
+type AmbiguousError struct {
+	Hash       string
+	Candidates []string // Names of the algorithms whose digests are as long as the hash
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("hash %s could be %s, specify the algorithm", e.Hash, strings.Join(e.Candidates, " or "))
+}
+
+// Normalize trims a hash and lowercases it, checking it is hexadecimal.
+func Normalize(hash string) (string, error) {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if hash == "" {
+		return "", fmt.Errorf("hash is empty")
+	}
+	for i := 0; i < len(hash); i++ {
+		if c := hash[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return "", fmt.Errorf("hash %q is not hexadecimal", hash)
+		}
+	}
+	return hash, nil
+}
+
+// Candidates returns the names of the algorithms whose digests are as long as a hexadecimal hash.
+func Candidates(hash string) []string {
+	var names []string
+	for _, algorithm := range algorithms {
+		if 2*algorithm.Size == len(hash) {
+			names = append(names, algorithm.Name)
+		}
+	}
+	return names
+}
+
+// Identify normalizes a hash and returns it along with the name of its algorithm.
+// A named algorithm must fit the length of the hash. When the name is empty, the algorithm is guessed
+// from the length, and an *AmbiguousError is returned when several algorithms fit.
+func Identify(hash, algorithm string) (string, string, error) {
+	hash, err := Normalize(hash)
+	if err != nil {
+		return "", "", err
+	}
+	candidates := Candidates(hash)
+	if len(candidates) == 0 {
+		return "", "", fmt.Errorf("hash %s has %d hex characters, which fits no supported algorithm", hash, len(hash))
+	}
+
+	if algorithm == "" {
+		if len(candidates) > 1 {
+			return hash, "", &AmbiguousError{Hash: hash, Candidates: candidates}
+		}
+		return hash, candidates[0], nil
+	}
+
+	named, err := Lookup(algorithm)
+	if err != nil {
+		return "", "", err
+	}
+	if err := named.Validate(hash); err != nil {
+		return "", "", fmt.Errorf("%v, it could be %s", err, strings.Join(candidates, " or "))
+	}
+	return hash, named.Name, nil
+}
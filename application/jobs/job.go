@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"time"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/hashing"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/keyspace"
+)
+
+// State is a step of the lifecycle of a job.
+type State string
+
+const (
+	Queued    State = "queued"    // Waiting for its first chunk to be handed to a worker
+	Running   State = "running"   // At least one chunk handed to a worker
+	Found     State = "found"     // Solution found
+	Exhausted State = "exhausted" // Keyspace searched without finding a solution
+	Cancelled State = "cancelled" // Cancelled before completion
+	Failed    State = "failed"    // Could not be processed
+)
+
+// Terminal reports whether no further transition can happen from this state.
+func (s State) Terminal() bool {
+	switch s {
+	case Found, Exhausted, Cancelled, Failed:
+		return true
+	}
+	return false
+}
+
+// Job is a hash submitted by a client, tracked from submission to completion.
+type Job struct {
+	ID         string            `json:"id"`
+	Submitter  string            `json:"submitter"`
+	Hash       string            `json:"hash"`
+	Algorithm  string            `json:"algorithm"` // Name of the hash algorithm, MD5 when empty
+	Keyspace   keyspace.Keyspace `json:"keyspace"`
+	Wordlist   string            `json:"wordlist,omitempty"` // Name of the wordlist searched instead of the keyspace, if any
+	Mask       string            `json:"mask,omitempty"`     // Hashcat-style mask searched instead of the keyspace, if any
+	Rules      string            `json:"rules,omitempty"`    // Name of the ruleset mangling the words of the wordlist, if any
+	State      State             `json:"state"`
+	Chunks     uint64            `json:"chunks"`             // Number of chunks the keyspace is cut into
+	Dispatched uint64            `json:"dispatched"`         // Number of chunks handed out to workers so far
+	Requeued   []uint64          `json:"requeued,omitempty"` // Chunks handed back by workers, to hand out again first
+	Completed  uint64            `json:"completed"`          // Number of chunks searched without finding the solution
+	Result     string            `json:"result,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	StartedAt  *time.Time        `json:"startedAt,omitempty"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+
+	CustomCharsets []string `json:"customCharsets,omitempty"` // Charsets ?1 to ?4 of the mask
+	TraceParent    string   `json:"traceParent,omitempty"`    // W3C trace context of the root span of the job
+}
+
+// Target identifies the hash of the job along with its algorithm.
+func (j Job) Target() string {
+	return hashing.Target(j.Algorithm, j.Hash)
+}
@@ -0,0 +1,289 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Persister saves jobs each time they change, so they survive a coordinator restart.
+type Persister interface {
+	SaveJob(ctx context.Context, job Job) error
+	DeleteJobs(ctx context.Context, ids ...string) error
+}
+
+// Registry keeps every job submitted to the coordinator and their current state.
+// Changes are saved while holding the lock, so the persister receives them in the order they were made.
+type Registry struct {
+	mu        sync.RWMutex
+	jobs      map[string]*Job
+	persister Persister
+}
+
+// NewRegistry creates an empty Registry saving its jobs through the given persister.
+func NewRegistry(persister Persister) *Registry {
+	return &Registry{
+		jobs:      make(map[string]*Job),
+		persister: persister,
+	}
+}
+
+// Restore puts back a job loaded from storage.
+func (r *Registry) Restore(job Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[job.ID] = &job
+}
+
+// Create registers a new queued job and returns a copy of it with its ID set.
+func (r *Registry) Create(job Job) Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.create(job)
+}
+
+// CreateIfRoom registers new queued jobs unless more than max jobs would then be unfinished.
+// The room is checked and the jobs created under the same lock, so concurrent batches never exceed max.
+// It returns copies of the jobs with their ID set, or false when none was created.
+func (r *Registry) CreateIfRoom(batch []Job, max int) ([]Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(batch) > 0 && r.countUnfinished()+len(batch) > max {
+		return nil, false
+	}
+	created := make([]Job, 0, len(batch))
+	for _, job := range batch {
+		created = append(created, r.create(job))
+	}
+	return created, true
+}
+
+// create registers a new queued job. It is called with the lock held.
+func (r *Registry) create(job Job) Job {
+	job.ID = uuid.New().String()
+	job.State = Queued
+	job.CreatedAt = time.Now()
+	r.jobs[job.ID] = &job
+
+	log.Printf("Job %s created for hash %s\n", job.ID, job.Hash)
+	r.persist(job)
+	return job
+}
+
+// Get returns a copy of the job with the given ID.
+func (r *Registry) Get(id string) (Job, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List returns a copy of every job, oldest first.
+func (r *Registry) List() []Job {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]Job, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		list = append(list, *job)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// countUnfinished returns the number of jobs queued or running. It is called with the lock held.
+func (r *Registry) countUnfinished() int {
+	count := 0
+	for _, job := range r.jobs {
+		if !job.State.Terminal() {
+			count++
+		}
+	}
+	return count
+}
+
+// CountByState returns the number of jobs in each state.
+func (r *Registry) CountByState() map[State]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[State]int)
+	for _, job := range r.jobs {
+		counts[job.State]++
+	}
+	return counts
+}
+
+// SetChunks records the number of chunks the keyspace of a job is cut into.
+func (r *Registry) SetChunks(id string, chunks uint64) {
+	r.update(id, func(job *Job) {
+		job.Chunks = chunks
+	})
+}
+
+// MarkDispatched records how many chunks of a job were handed out and which ones were handed back,
+// moving it to the running state.
+func (r *Registry) MarkDispatched(id string, dispatched uint64, requeued []uint64) {
+	r.update(id, func(job *Job) {
+		job.Dispatched = dispatched
+		job.Requeued = requeued
+		if job.State == Queued {
+			now := time.Now()
+			job.State = Running
+			job.StartedAt = &now
+		}
+	})
+}
+
+// Requeue records the chunks of a job handed back to the queue, along with how many chunks were handed out.
+func (r *Registry) Requeue(id string, dispatched uint64, requeued []uint64) {
+	r.update(id, func(job *Job) {
+		job.Dispatched = dispatched
+		job.Requeued = requeued
+	})
+}
+
+// MarkCompleted records how many chunks of a job were searched without finding the solution.
+func (r *Registry) MarkCompleted(id string, completed uint64) {
+	r.update(id, func(job *Job) {
+		job.Completed = completed
+	})
+}
+
+// MarkFound records the solution of a hash on every unfinished job targeting it, and returns them.
+// The hash is identified along with its algorithm, as returned by Job.Target.
+func (r *Registry) MarkFound(target, result string) []Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var solved []Job
+	for _, job := range r.jobs {
+		if job.Target() != target || job.State.Terminal() {
+			continue
+		}
+		job.Result = result
+		r.finish(job, Found)
+		solved = append(solved, *job)
+	}
+	r.persist(solved...)
+	return solved
+}
+
+// MarkSolved records the solution of a single job, already known without searching.
+func (r *Registry) MarkSolved(id, result string) (Job, error) {
+	return r.transition(id, Found, func(job *Job) {
+		job.Result = result
+	})
+}
+
+// MarkExhausted records that the keyspace of a job was searched without finding a solution.
+func (r *Registry) MarkExhausted(id string) (Job, error) {
+	return r.transition(id, Exhausted, nil)
+}
+
+// Cancel marks an unfinished job as cancelled.
+func (r *Registry) Cancel(id string) (Job, error) {
+	return r.transition(id, Cancelled, nil)
+}
+
+// Fail records that a job could not be processed.
+func (r *Registry) Fail(id, reason string) (Job, error) {
+	return r.transition(id, Failed, func(job *Job) {
+		job.Error = reason
+	})
+}
+
+// Expire forgets the jobs finished before the given time, along with their saved copy, and returns how many.
+func (r *Registry) Expire(before time.Time) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var expired []string
+	for id, job := range r.jobs {
+		if job.State.Terminal() && job.FinishedAt != nil && job.FinishedAt.Before(before) {
+			expired = append(expired, id)
+			delete(r.jobs, id)
+		}
+	}
+	if len(expired) == 0 {
+		return 0
+	}
+	if err := r.persister.DeleteJobs(context.Background(), expired...); err != nil {
+		log.Printf("Failed to delete %d expired jobs: %v\n", len(expired), err)
+	}
+	return len(expired)
+}
+
+// StartExpiry periodically forgets the jobs finished for longer than the retention, until the context is done.
+func (r *Registry) StartExpiry(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(min(retention, time.Minute))
+	defer ticker.Stop()
+	for {
+		if expired := r.Expire(time.Now().Add(-retention)); expired > 0 {
+			log.Printf("Forgot %d jobs finished for more than %s\n", expired, retention)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// update applies a change to an unfinished job.
+func (r *Registry) update(id string, change func(job *Job)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok || job.State.Terminal() {
+		return
+	}
+	change(job)
+	r.persist(*job)
+}
+
+// transition moves an unfinished job to a terminal state, applying an optional change first.
+func (r *Registry) transition(id string, state State, change func(job *Job)) (Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("job %s not found", id)
+	}
+	if job.State.Terminal() {
+		return *job, fmt.Errorf("job %s is already %s", id, job.State)
+	}
+	if change != nil {
+		change(job)
+	}
+	r.finish(job, state)
+	r.persist(*job)
+	return *job, nil
+}
+
+// finish moves a job to a terminal state.
+func (r *Registry) finish(job *Job, state State) {
+	now := time.Now()
+	job.State = state
+	job.FinishedAt = &now
+	log.Printf("Job %s is %s\n", job.ID, state)
+}
+
+// persist saves changed jobs, logging failures since the in-memory state stays authoritative.
+// It is called with the lock held.
+func (r *Registry) persist(changed ...Job) {
+	for _, job := range changed {
+		if err := r.persister.SaveJob(context.Background(), job); err != nil {
+			log.Printf("Failed to persist job %s: %v\n", job.ID, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package keyspace
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+const (
+	Lower  = "abcdefghijklmnopqrstuvwxyz"
+	Upper  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Digits = "0123456789"
+	Alnum  = Digits + Lower + Upper
+)
+
+// DefaultCharset is the alphabet enumerated by the workers, in order.
+const DefaultCharset = Alnum
+
+// presets maps charset names accepted from clients to their characters.
+var presets = map[string]string{
+	"lower":  Lower,
+	"upper":  Upper,
+	"digits": Digits,
+	"alnum":  Alnum,
+}
+
+// ParseCharset resolves a preset name (lower, upper, digits, alnum) or returns the value as a custom charset.
+func ParseCharset(value string) string {
+	if charset, ok := presets[value]; ok {
+		return charset
+	}
+	return value
+}
+
+// Keyspace describes every word of MinLength to MaxLength characters over Charset.
+// Words are ordered by length first, then lexicographically following the order of Charset.
+type Keyspace struct {
+	Charset   string `json:"charset"`
+	MinLength int    `json:"minLength"`
+	MaxLength int    `json:"maxLength"`
+}
+
+// Default returns the keyspace historically searched by the workers ("0" to "ZZZZ").
+func Default() Keyspace {
+	return Keyspace{
+		Charset:   DefaultCharset,
+		MinLength: 1,
+		MaxLength: 4,
+	}
+}
+
+// Validate reports whether the keyspace can be enumerated and sent to the workers.
+func (k Keyspace) Validate() error {
+	if k.Charset == "" {
+		return errors.New("charset is empty")
+	}
+	seen := make(map[byte]bool, len(k.Charset))
+	for i := 0; i < len(k.Charset); i++ {
+		c := k.Charset[i]
+		// Workers receive the charset inside a space separated message
+		if c <= ' ' || c > '~' {
+			return fmt.Errorf("charset may only contain printable ASCII characters, found %q", c)
+		}
+		if seen[c] {
+			return fmt.Errorf("charset contains %q more than once", c)
+		}
+		seen[c] = true
+	}
+
+	if k.MinLength < 1 {
+		return errors.New("minimum length must be at least 1")
+	}
+	if k.MaxLength < k.MinLength {
+		return fmt.Errorf("maximum length %d is lower than minimum length %d", k.MaxLength, k.MinLength)
+	}
+
+	base := uint64(len(k.Charset))
+	var size uint64
+	for length := k.MinLength; length <= k.MaxLength; length++ {
+		count, ok := checkedPow(base, length)
+		if !ok {
+			return fmt.Errorf("keyspace of %d characters up to length %d is too large", base, k.MaxLength)
+		}
+		var carry uint64
+		size, carry = bits.Add64(size, count, 0)
+		if carry != 0 {
+			return fmt.Errorf("keyspace of %d characters up to length %d is too large", base, k.MaxLength)
+		}
+	}
+	return nil
+}
+
+// Size returns the number of words in the keyspace.
+func (k Keyspace) Size() uint64 {
+	base := uint64(len(k.Charset))
+	var size uint64
+	for length := k.MinLength; length <= k.MaxLength; length++ {
+		size += pow(base, length)
+	}
+	return size
+}
+
+// Word returns the word found at the given position of the keyspace.
+func (k Keyspace) Word(index uint64) string {
+	base := uint64(len(k.Charset))
+	length := k.MinLength
+	for {
+		count := pow(base, length)
+		if index < count || length >= k.MaxLength {
+			break
+		}
+		index -= count
+		length++
+	}
+
+	word := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		word[i] = k.Charset[index%base]
+		index /= base
+	}
+	return string(word)
+}
+
+// Index returns the position of a word in the keyspace.
+func (k Keyspace) Index(word string) (uint64, error) {
+	if len(word) < k.MinLength || len(word) > k.MaxLength {
+		return 0, fmt.Errorf("word %q is not between %d and %d characters long", word, k.MinLength, k.MaxLength)
+	}
+
+	base := uint64(len(k.Charset))
+	var index uint64
+	for length := k.MinLength; length < len(word); length++ {
+		index += pow(base, length)
+	}
+
+	var offset uint64
+	for i := 0; i < len(word); i++ {
+		digit := strings.IndexByte(k.Charset, word[i])
+		if digit < 0 {
+			return 0, fmt.Errorf("character %q of word %q is not in the charset", word[i], word)
+		}
+		offset = offset*base + uint64(digit)
+	}
+	return index + offset, nil
+}
+
+// pow returns base raised to the power of exp.
+func pow(base uint64, exp int) uint64 {
+	result := uint64(1)
+	for i := 0; i < exp; i++ {
+		result *= base
+	}
+	return result
+}
+
+// checkedPow returns base raised to the power of exp, and false if it overflows.
+func checkedPow(base uint64, exp int) (uint64, bool) {
+	result := uint64(1)
+	for i := 0; i < exp; i++ {
+		hi, lo := bits.Mul64(result, base)
+		if hi != 0 {
+			return 0, false
+		}
+		result = lo
+	}
+	return result, true
+}
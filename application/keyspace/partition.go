@@ -0,0 +1,81 @@
+package keyspace
+
+import "strconv"
+
+// Chunk is an inclusive range of words handed to a single worker.
+type Chunk struct {
+	Begin string
+	End   string
+}
+
+// Partitioner cuts a keyspace into chunks of at most ChunkSize words.
+type Partitioner struct {
+	Keyspace  Keyspace
+	ChunkSize uint64
+}
+
+// NewPartitioner creates a Partitioner for the given keyspace.
+func NewPartitioner(k Keyspace, chunkSize uint64) *Partitioner {
+	if chunkSize == 0 {
+		chunkSize = 1
+	}
+	return &Partitioner{
+		Keyspace:  k,
+		ChunkSize: chunkSize,
+	}
+}
+
+// Count returns the number of chunks the keyspace is cut into.
+func (p *Partitioner) Count() uint64 {
+	size := p.Keyspace.Size()
+	return size/p.ChunkSize + min(size%p.ChunkSize, 1)
+}
+
+// Chunk returns the chunk found at the given position.
+func (p *Partitioner) Chunk(index uint64) Chunk {
+	first := index * p.ChunkSize
+	last := first + p.ChunkSize - 1
+	if size := p.Keyspace.Size(); last >= size || last < first {
+		last = size - 1
+	}
+	return Chunk{
+		Begin: p.Keyspace.Word(first),
+		End:   p.Keyspace.Word(last),
+	}
+}
+
+// RangePartitioner cuts Size numbered candidates, such as the lines of a wordlist, into chunks of at most ChunkSize.
+// The bounds of the chunks are the decimal numbers of their first and last candidates, starting from 0.
+type RangePartitioner struct {
+	Size      uint64
+	ChunkSize uint64
+}
+
+// NewRangePartitioner creates a RangePartitioner for the given number of candidates.
+func NewRangePartitioner(size, chunkSize uint64) *RangePartitioner {
+	if chunkSize == 0 {
+		chunkSize = 1
+	}
+	return &RangePartitioner{
+		Size:      size,
+		ChunkSize: chunkSize,
+	}
+}
+
+// Count returns the number of chunks the candidates are cut into.
+func (p *RangePartitioner) Count() uint64 {
+	return p.Size/p.ChunkSize + min(p.Size%p.ChunkSize, 1)
+}
+
+// Chunk returns the range of candidates found at the given position.
+func (p *RangePartitioner) Chunk(index uint64) Chunk {
+	first := index * p.ChunkSize
+	last := first + p.ChunkSize - 1
+	if last >= p.Size || last < first {
+		last = p.Size - 1
+	}
+	return Chunk{
+		Begin: strconv.FormatUint(first, 10),
+		End:   strconv.FormatUint(last, 10),
+	}
+}
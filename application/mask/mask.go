@@ -0,0 +1,183 @@
+// Package mask parses hashcat-style masks, such as ?u?l?l?l?d?d, and enumerates the words they describe.
+// Words are ordered like numbers whose digits are the positions of the mask, the last position varying fastest.
+package mask
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// MaxCustomCharsets is the number of custom charsets a mask may refer to, as ?1 to ?4.
+const MaxCustomCharsets = 4
+
+// builtins maps the built-in charset placeholders to their characters.
+var builtins = map[byte]string{
+	'l': "abcdefghijklmnopqrstuvwxyz",
+	'u': "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	'd': "0123456789",
+	'h': "0123456789abcdef",
+	'H': "0123456789ABCDEF",
+	's': " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+}
+
+func init() {
+	builtins['a'] = builtins['l'] + builtins['u'] + builtins['d'] + builtins['s']
+}
+
+// Mask is a parsed mask: the characters each position of the words takes, in order.
+type Mask struct {
+	Positions []string
+}
+
+// Parse reads a mask made of literal characters and placeholders: ?l, ?u, ?d, ?h, ?H, ?s, ?a,
+// ?1 to ?4 for the custom charsets, and ?? for a literal '?'.
+// Custom charsets are literal characters and built-in placeholders, e.g. "?l?d" or "abc?d".
+// Masks and custom charsets are made of printable ASCII characters without spaces, which ?s stands for.
+func Parse(mask string, custom []string) (Mask, error) {
+	if len(custom) > MaxCustomCharsets {
+		return Mask{}, fmt.Errorf("at most %d custom charsets are supported, got %d", MaxCustomCharsets, len(custom))
+	}
+	expanded := make([]string, len(custom))
+	for i, definition := range custom {
+		charset, err := expand(definition, nil)
+		if err != nil {
+			return Mask{}, fmt.Errorf("custom charset %d: %v", i+1, err)
+		}
+		if charset == "" {
+			return Mask{}, fmt.Errorf("custom charset %d is empty", i+1)
+		}
+		expanded[i] = charset
+	}
+
+	var m Mask
+	for i := 0; i < len(mask); i++ {
+		c := mask[i]
+		if err := checkPrintable(c); err != nil {
+			return Mask{}, err
+		}
+		if c != '?' {
+			m.Positions = append(m.Positions, string(c))
+			continue
+		}
+		if i++; i == len(mask) {
+			return Mask{}, errors.New("mask ends with an incomplete placeholder")
+		}
+		charset, err := placeholder(mask[i], expanded)
+		if err != nil {
+			return Mask{}, err
+		}
+		m.Positions = append(m.Positions, charset)
+	}
+	if len(m.Positions) == 0 {
+		return Mask{}, errors.New("mask is empty")
+	}
+	return m, nil
+}
+
+// expand resolves the placeholders of a custom charset, removing repeated characters.
+func expand(definition string, custom []string) (string, error) {
+	var charset strings.Builder
+	seen := make(map[byte]bool)
+	add := func(characters string) {
+		for i := 0; i < len(characters); i++ {
+			if !seen[characters[i]] {
+				seen[characters[i]] = true
+				charset.WriteByte(characters[i])
+			}
+		}
+	}
+
+	for i := 0; i < len(definition); i++ {
+		c := definition[i]
+		if err := checkPrintable(c); err != nil {
+			return "", err
+		}
+		if c != '?' {
+			add(string(c))
+			continue
+		}
+		if i++; i == len(definition) {
+			return "", errors.New("charset ends with an incomplete placeholder")
+		}
+		characters, err := placeholder(definition[i], custom)
+		if err != nil {
+			return "", err
+		}
+		add(characters)
+	}
+	return charset.String(), nil
+}
+
+// placeholder returns the characters a placeholder stands for, given the character that follows '?'.
+func placeholder(c byte, custom []string) (string, error) {
+	if c == '?' {
+		return "?", nil
+	}
+	if charset, ok := builtins[c]; ok {
+		return charset, nil
+	}
+	if c >= '1' && c <= '0'+MaxCustomCharsets {
+		if index := int(c - '1'); index < len(custom) {
+			return custom[index], nil
+		}
+		return "", fmt.Errorf("custom charset ?%c is not defined", c)
+	}
+	return "", fmt.Errorf("unknown placeholder ?%c", c)
+}
+
+// checkPrintable rejects the characters that cannot be sent to the workers inside a space separated message.
+func checkPrintable(c byte) error {
+	if c <= ' ' || c > '~' {
+		return fmt.Errorf("only printable ASCII characters without spaces are allowed, found %q (use ?s for a space)", c)
+	}
+	return nil
+}
+
+// Size returns the exact number of words of the mask, failing when it does not fit 64 bits.
+func (m Mask) Size() (uint64, error) {
+	size := uint64(1)
+	for _, charset := range m.Positions {
+		hi, lo := bits.Mul64(size, uint64(len(charset)))
+		if hi != 0 {
+			return 0, errors.New("mask describes more than 2^64 words")
+		}
+		size = lo
+	}
+	return size, nil
+}
+
+// Digits returns the position in its charset of the character of each position of the word at the given index.
+func (m Mask) Digits(index uint64) []int {
+	digits := make([]int, len(m.Positions))
+	for i := len(m.Positions) - 1; i >= 0; i-- {
+		base := uint64(len(m.Positions[i]))
+		digits[i] = int(index % base)
+		index /= base
+	}
+	return digits
+}
+
+// Word returns the word found at the given index.
+func (m Mask) Word(index uint64) string {
+	word := make([]byte, len(m.Positions))
+	for i, digit := range m.Digits(index) {
+		word[i] = m.Positions[i][digit]
+	}
+	return string(word)
+}
+
+// Next moves a word and its digits to the following word, and reports false once the last word was passed.
+func (m Mask) Next(word []byte, digits []int) bool {
+	for i := len(word) - 1; i >= 0; i-- {
+		digits[i]++
+		if digits[i] < len(m.Positions[i]) {
+			word[i] = m.Positions[i][digits[i]]
+			return true
+		}
+		digits[i] = 0
+		word[i] = m.Positions[i][0]
+	}
+	return false
+}
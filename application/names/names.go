@@ -0,0 +1,24 @@
+// Package names checks the names files are uploaded to the coordinator under, such as wordlists and rulesets.
+package names
+
+import "fmt"
+
+// maxLength bounds the length of names, which are used as file names.
+const maxLength = 128
+
+// Validate reports whether a name can identify an uploaded file: letters, digits, '.', '-' and '_', not starting with '.'.
+func Validate(name string) error {
+	if name == "" || len(name) > maxLength {
+		return fmt.Errorf("name must be 1 to %d characters long", maxLength)
+	}
+	if name[0] == '.' {
+		return fmt.Errorf("name %q may not start with '.'", name)
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return fmt.Errorf("name %q may only contain letters, digits, '.', '-' and '_'", name)
+		}
+	}
+	return nil
+}
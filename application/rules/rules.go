@@ -0,0 +1,289 @@
+// Package rules parses word-mangling rules written in a subset of the hashcat/John the Ripper syntax,
+// and applies them to the words of a wordlist.
+//
+// A rule is a sequence of functions applied in turn, such as "c $1 $2" (capitalize, then append "12").
+// Positions are written 0-9 then A-Z for 10 to 35. A function referring to a position beyond the word
+// leaves it unchanged, as hashcat does.
+package rules
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// arguments lists the supported functions with the kind of each of their arguments:
+// 'N' for a position or a count, 'X' for a character.
+var arguments = map[byte]string{
+	':':  "",   // Leave the word unchanged
+	'l':  "",   // Lowercase every letter
+	'u':  "",   // Uppercase every letter
+	'c':  "",   // Capitalize: uppercase the first letter, lowercase the others
+	'C':  "",   // Invert capitalize: lowercase the first letter, uppercase the others
+	't':  "",   // Toggle the case of every letter
+	'T':  "N",  // Toggle the case of the letter at position N
+	'r':  "",   // Reverse
+	'd':  "",   // Duplicate: "abc" becomes "abcabc"
+	'p':  "N",  // Append N copies of the word
+	'f':  "",   // Reflect: "abc" becomes "abccba"
+	'{':  "",   // Rotate left: "abc" becomes "bca"
+	'}':  "",   // Rotate right: "abc" becomes "cab"
+	'$':  "X",  // Append X
+	'^':  "X",  // Prepend X
+	'[':  "",   // Delete the first character
+	']':  "",   // Delete the last character
+	'D':  "N",  // Delete the character at position N
+	'x':  "NN", // Extract M characters from position N
+	'O':  "NN", // Omit M characters from position N
+	'i':  "NX", // Insert X at position N
+	'o':  "NX", // Overwrite the character at position N with X
+	'\'': "N",  // Truncate the word to N characters
+	's':  "XX", // Replace every X with Y
+	'@':  "X",  // Purge every X
+	'z':  "N",  // Duplicate the first character N times
+	'Z':  "N",  // Duplicate the last character N times
+	'q':  "",   // Duplicate every character
+	'k':  "",   // Swap the first two characters
+	'K':  "",   // Swap the last two characters
+	'*':  "NN", // Swap the characters at positions N and M
+}
+
+// function is a single step of a rule.
+type function struct {
+	name      byte
+	positions [2]int  // Arguments of kind 'N', in order
+	chars     [2]byte // Arguments of kind 'X', in order
+}
+
+// Rule is a parsed rule, applying its functions in order.
+type Rule struct {
+	source    string
+	functions []function
+}
+
+// Parse reads a rule such as "c $1 $2". Spaces between functions are ignored.
+func Parse(source string) (Rule, error) {
+	rule := Rule{source: source}
+	for i := 0; i < len(source); i++ {
+		name := source[i]
+		if name == ' ' || name == '\t' {
+			continue
+		}
+		kinds, ok := arguments[name]
+		if !ok {
+			return Rule{}, fmt.Errorf("unknown function %q at column %d", name, i+1)
+		}
+
+		f := function{name: name}
+		var positions, chars int
+		for _, kind := range []byte(kinds) {
+			if i++; i == len(source) {
+				return Rule{}, fmt.Errorf("function %q is missing arguments", name)
+			}
+			if kind == 'X' {
+				f.chars[chars] = source[i]
+				chars++
+				continue
+			}
+			position, err := parsePosition(source[i])
+			if err != nil {
+				return Rule{}, fmt.Errorf("function %q: %v", name, err)
+			}
+			f.positions[positions] = position
+			positions++
+		}
+		rule.functions = append(rule.functions, f)
+	}
+	if len(rule.functions) == 0 {
+		return Rule{}, fmt.Errorf("rule is empty")
+	}
+	return rule, nil
+}
+
+// parsePosition reads a position written 0-9 then A-Z.
+func parsePosition(c byte) (int, error) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), nil
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10, nil
+	}
+	return 0, fmt.Errorf("invalid position %q, expected 0-9 or A-Z", c)
+}
+
+// ParseFile reads a ruleset, one rule per line. Empty lines and lines starting with '#' are skipped.
+func ParseFile(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule, err := Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r Rule) String() string {
+	return r.source
+}
+
+// Apply returns the word mangled by the rule. The word itself is left untouched.
+func (r Rule) Apply(word []byte) []byte {
+	result := bytes.Clone(word)
+	for _, f := range r.functions {
+		result = f.apply(result)
+	}
+	return result
+}
+
+// apply runs the function on a word it may modify, and returns the result.
+func (f function) apply(w []byte) []byte {
+	n, m := f.positions[0], f.positions[1]
+	x, y := f.chars[0], f.chars[1]
+
+	switch f.name {
+	case 'l':
+		return bytes.ToLower(w)
+	case 'u':
+		return bytes.ToUpper(w)
+	case 'c':
+		w = bytes.ToLower(w)
+		if len(w) > 0 {
+			w[0] = upper(w[0])
+		}
+	case 'C':
+		w = bytes.ToUpper(w)
+		if len(w) > 0 {
+			w[0] = lower(w[0])
+		}
+	case 't':
+		for i := range w {
+			w[i] = toggle(w[i])
+		}
+	case 'T':
+		if n < len(w) {
+			w[n] = toggle(w[n])
+		}
+	case 'r':
+		for i, j := 0, len(w)-1; i < j; i, j = i+1, j-1 {
+			w[i], w[j] = w[j], w[i]
+		}
+	case 'd':
+		return append(w, w...)
+	case 'p':
+		return bytes.Repeat(w, n+1)
+	case 'f':
+		reflected := bytes.Clone(w)
+		for i := len(w) - 1; i >= 0; i-- {
+			reflected = append(reflected, w[i])
+		}
+		return reflected
+	case '{':
+		if len(w) > 0 {
+			return append(w[1:], w[0])
+		}
+	case '}':
+		if len(w) > 0 {
+			return append([]byte{w[len(w)-1]}, w[:len(w)-1]...)
+		}
+	case '$':
+		return append(w, x)
+	case '^':
+		return append([]byte{x}, w...)
+	case '[':
+		if len(w) > 0 {
+			return w[1:]
+		}
+	case ']':
+		if len(w) > 0 {
+			return w[:len(w)-1]
+		}
+	case 'D':
+		if n < len(w) {
+			return append(w[:n], w[n+1:]...)
+		}
+	case 'x':
+		if n+m <= len(w) {
+			return w[n : n+m]
+		}
+	case 'O':
+		if n+m <= len(w) {
+			return append(w[:n], w[n+m:]...)
+		}
+	case 'i':
+		if n <= len(w) {
+			return append(w[:n], append([]byte{x}, w[n:]...)...)
+		}
+	case 'o':
+		if n < len(w) {
+			w[n] = x
+		}
+	case '\'':
+		if n < len(w) {
+			return w[:n]
+		}
+	case 's':
+		return bytes.ReplaceAll(w, []byte{x}, []byte{y})
+	case '@':
+		return bytes.ReplaceAll(w, []byte{x}, nil)
+	case 'z':
+		if len(w) > 0 {
+			return append(bytes.Repeat(w[:1], n), w...)
+		}
+	case 'Z':
+		if len(w) > 0 {
+			return append(w, bytes.Repeat(w[len(w)-1:], n)...)
+		}
+	case 'q':
+		doubled := make([]byte, 0, 2*len(w))
+		for _, c := range w {
+			doubled = append(doubled, c, c)
+		}
+		return doubled
+	case 'k':
+		if len(w) > 1 {
+			w[0], w[1] = w[1], w[0]
+		}
+	case 'K':
+		if len(w) > 1 {
+			w[len(w)-1], w[len(w)-2] = w[len(w)-2], w[len(w)-1]
+		}
+	case '*':
+		if n < len(w) && m < len(w) {
+			w[n], w[m] = w[m], w[n]
+		}
+	}
+	return w
+}
+
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+func lower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c - 'A' + 'a'
+	}
+	return c
+}
+
+func toggle(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return upper(c)
+	}
+	return lower(c)
+}
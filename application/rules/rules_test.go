@@ -0,0 +1,124 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		rule string
+		err  string
+	}{
+		{"", "rule is empty"},
+		{"   ", "rule is empty"},
+		{"w", "unknown function 'w' at column 1"},
+		{"c w", "unknown function 'w' at column 3"},
+		{"$", "function '$' is missing arguments"},
+		{"s", "function 's' is missing arguments"},
+		{"sa", "function 's' is missing arguments"},
+		{"x0", "function 'x' is missing arguments"},
+		{"T!", "function 'T': invalid position '!', expected 0-9 or A-Z"},
+		{"Ta", "function 'T': invalid position 'a', expected 0-9 or A-Z"},
+		{"i!a", "function 'i': invalid position '!', expected 0-9 or A-Z"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.rule)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.rule, err, tt.err)
+		}
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	rules, err := ParseFile(strings.NewReader("# comment\n\n:\r\nc $1\n  \nsa4 se3\n"))
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	var sources []string
+	for _, rule := range rules {
+		sources = append(sources, rule.String())
+	}
+	if got, want := strings.Join(sources, "|"), ":|c $1|sa4 se3"; got != want {
+		t.Errorf("ParseFile() rules = %q, want %q", got, want)
+	}
+
+	_, err = ParseFile(strings.NewReader(":\nc\nw\n"))
+	if err == nil || err.Error() != "line 3: unknown function 'w' at column 1" {
+		t.Errorf("ParseFile() error = %v, want the line of the invalid rule", err)
+	}
+}
+
+// TestApply checks the outputs documented by hashcat for its rule functions.
+func TestApply(t *testing.T) {
+	tests := []struct {
+		rule string
+		word string
+		want string
+	}{
+		{":", "p@ssW0rd", "p@ssW0rd"},
+		{"l", "p@ssW0rd", "p@ssw0rd"},
+		{"u", "p@ssW0rd", "P@SSW0RD"},
+		{"c", "p@ssW0rd", "P@ssw0rd"},
+		{"C", "p@ssW0rd", "p@SSW0RD"},
+		{"t", "p@ssW0rd", "P@SSw0RD"},
+		{"T3", "p@ssW0rd", "p@sSW0rd"},
+		{"T9", "abc", "abc"},
+		{"r", "p@ssW0rd", "dr0Wss@p"},
+		{"d", "p@ssW0rd", "p@ssW0rdp@ssW0rd"},
+		{"p2", "p@ssW0rd", "p@ssW0rdp@ssW0rdp@ssW0rd"},
+		{"p0", "abc", "abc"},
+		{"f", "p@ssW0rd", "p@ssW0rddr0Wss@p"},
+		{"{", "p@ssW0rd", "@ssW0rdp"},
+		{"{", "", ""},
+		{"}", "p@ssW0rd", "dp@ssW0r"},
+		{"}", "", ""},
+		{"$1", "p@ssW0rd", "p@ssW0rd1"},
+		{"^1", "p@ssW0rd", "1p@ssW0rd"},
+		{"[", "p@ssW0rd", "@ssW0rd"},
+		{"]", "p@ssW0rd", "p@ssW0r"},
+		{"[", "", ""},
+		{"D3", "p@ssW0rd", "p@sW0rd"},
+		{"D3", "abc", "abc"},
+		{"x04", "p@ssW0rd", "p@ss"},
+		{"x23", "abc", "abc"},
+		{"O12", "p@ssW0rd", "psW0rd"},
+		{"O22", "abc", "abc"},
+		{"i4!", "p@ssW0rd", "p@ss!W0rd"},
+		{"i3!", "abc", "abc!"},
+		{"i4!", "abc", "abc"},
+		{"o3$", "p@ssW0rd", "p@s$W0rd"},
+		{"o3$", "abc", "abc"},
+		{"'6", "p@ssW0rd", "p@ssW0"},
+		{"'3", "abc", "abc"},
+		{"ss$", "p@ssW0rd", "p@$$W0rd"},
+		{"@s", "p@ssW0rd", "p@W0rd"},
+		{"z2", "p@ssW0rd", "ppp@ssW0rd"},
+		{"z2", "", ""},
+		{"Z2", "p@ssW0rd", "p@ssW0rddd"},
+		{"Z2", "", ""},
+		{"q", "p@ssW0rd", "pp@@ssssWW00rrdd"},
+		{"k", "p@ssW0rd", "@pssW0rd"},
+		{"K", "p@ssW0rd", "p@ssW0dr"},
+		{"*34", "p@ssW0rd", "p@sWs0rd"},
+		{"*39", "abc", "abc"},
+		{"$ ", "ab", "ab "},
+		{"c $1 $2 $3", "password", "Password123"},
+		{"sa4 se3 so0", "password", "p4ssw0rd"},
+		{"r {", "abc", "bac"},
+		{"TA", "abcdefghijk", "abcdefghijK"},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.rule, err)
+		}
+		word := []byte(tt.word)
+		if got := string(rule.Apply(word)); got != tt.want {
+			t.Errorf("%q applied to %q = %q, want %q", tt.rule, tt.word, got, tt.want)
+		}
+		if string(word) != tt.word {
+			t.Errorf("%q modified its input %q into %q", tt.rule, tt.word, word)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package scaling
+
+import (
+	"fmt"
+	"time"
+)
+
+// Inputs are the measurements a policy decides the number of replicas from.
+type Inputs struct {
+	QueueLength     int     // Chunks waiting for a worker
+	BusyWorkers     int     // Connected workers searching a chunk
+	IdleWorkers     int     // Connected workers waiting for a chunk
+	CurrentReplicas int     // Replicas requested from the orchestrator
+	Throughput      float64 // Chunks completed per second over the recent past
+}
+
+// Outstanding returns the number of chunks either queued or being searched.
+func (in Inputs) Outstanding() int {
+	return in.QueueLength + in.BusyWorkers
+}
+
+func (in Inputs) String() string {
+	return fmt.Sprintf("queue: %d, busy: %d, idle: %d, replicas: %d, throughput: %.2f chunks/s",
+		in.QueueLength, in.BusyWorkers, in.IdleWorkers, in.CurrentReplicas, in.Throughput)
+}
+
+// Decision is the number of replicas wanted by a policy along with why.
+type Decision struct {
+	Replicas int
+	Reason   string
+}
+
+// Policy decides how many worker replicas are needed.
+type Policy interface {
+	// Name identifies the policy in logs.
+	Name() string
+
+	// Decide returns the wanted number of replicas, which may equal the current one.
+	Decide(in Inputs, now time.Time) Decision
+
+	// Applied is told once the orchestrator scaled to a number of replicas decided by the policy.
+	Applied(replicas int, now time.Time)
+}
+
+// Bounds limits the number of replicas any policy can ask for.
+type Bounds struct {
+	Min int
+	Max int
+}
+
+// clamp keeps a number of replicas within the bounds.
+func (b Bounds) clamp(replicas int) int {
+	if replicas < b.Min {
+		return b.Min
+	}
+	if replicas > b.Max {
+		return b.Max
+	}
+	return replicas
+}
@@ -0,0 +1,55 @@
+package scaling
+
+import (
+	"fmt"
+	"time"
+)
+
+// StepPolicy adds or removes Step replicas when the load per replica leaves the [DownThreshold, UpThreshold] band.
+// The gap between both thresholds and the cooldowns after each change keep the replicas from flapping.
+type StepPolicy struct {
+	Bounds        Bounds
+	Step          int
+	UpThreshold   float64       // Outstanding chunks per replica above which replicas are added
+	DownThreshold float64       // Outstanding chunks per replica below which replicas are removed
+	UpCooldown    time.Duration // Minimum delay after any change before scaling up
+	DownCooldown  time.Duration // Minimum delay after any change before scaling down
+	lastChange    time.Time     // When the last change was applied
+}
+
+func (p *StepPolicy) Name() string {
+	return "step"
+}
+
+func (p *StepPolicy) Decide(in Inputs, now time.Time) Decision {
+	current := in.CurrentReplicas
+	if clamped := p.Bounds.clamp(current); clamped != current {
+		return Decision{Replicas: clamped, Reason: fmt.Sprintf("%d replicas is out of bounds", current)}
+	}
+
+	load := float64(in.Outstanding())
+	if current > 0 {
+		load /= float64(current)
+	}
+	since := now.Sub(p.lastChange)
+
+	switch {
+	case load > p.UpThreshold && current < p.Bounds.Max:
+		if since < p.UpCooldown {
+			return Decision{Replicas: current, Reason: fmt.Sprintf("load %.2f above %.2f, up cooldown %s left", load, p.UpThreshold, p.UpCooldown-since)}
+		}
+		return Decision{Replicas: p.Bounds.clamp(current + p.Step), Reason: fmt.Sprintf("load %.2f above %.2f", load, p.UpThreshold)}
+
+	case load < p.DownThreshold && current > p.Bounds.Min:
+		if since < p.DownCooldown {
+			return Decision{Replicas: current, Reason: fmt.Sprintf("load %.2f below %.2f, down cooldown %s left", load, p.DownThreshold, p.DownCooldown-since)}
+		}
+		return Decision{Replicas: p.Bounds.clamp(current - p.Step), Reason: fmt.Sprintf("load %.2f below %.2f", load, p.DownThreshold)}
+	}
+	return Decision{Replicas: current, Reason: fmt.Sprintf("load %.2f within [%.2f, %.2f]", load, p.DownThreshold, p.UpThreshold)}
+}
+
+// Applied starts the cooldowns. A change the orchestrator failed to apply is retried at the next decision.
+func (p *StepPolicy) Applied(replicas int, now time.Time) {
+	p.lastChange = now
+}
@@ -0,0 +1,72 @@
+package scaling
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStepPolicyDecide(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		in         Inputs
+		lastChange time.Time // Zero when no change was applied yet
+		now        time.Time
+		want       int
+	}{
+		{"within band", Inputs{QueueLength: 4, BusyWorkers: 2, CurrentReplicas: 2}, time.Time{}, start, 2},
+		{"on the up threshold", Inputs{QueueLength: 6, CurrentReplicas: 2}, time.Time{}, start, 2},
+		{"above band", Inputs{QueueLength: 10, BusyWorkers: 2, CurrentReplicas: 2}, time.Time{}, start, 4},
+		{"below band", Inputs{BusyWorkers: 1, CurrentReplicas: 4}, time.Time{}, start, 2},
+		{"step clamped to max", Inputs{QueueLength: 50, CurrentReplicas: 5}, time.Time{}, start, 6},
+		{"step clamped to min", Inputs{CurrentReplicas: 2}, time.Time{}, start, 1},
+		{"at max", Inputs{QueueLength: 50, CurrentReplicas: 6}, time.Time{}, start, 6},
+		{"at min", Inputs{CurrentReplicas: 1}, time.Time{}, start, 1},
+		{"above max", Inputs{QueueLength: 50, CurrentReplicas: 9}, start, start, 6},
+		{"below min", Inputs{CurrentReplicas: 0}, start, start, 1},
+		{"up cooldown", Inputs{QueueLength: 10, CurrentReplicas: 2}, start, start.Add(29 * time.Second), 2},
+		{"up cooldown over", Inputs{QueueLength: 10, CurrentReplicas: 2}, start, start.Add(30 * time.Second), 4},
+		{"down cooldown", Inputs{CurrentReplicas: 4}, start, start.Add(119 * time.Second), 4},
+		{"down cooldown over", Inputs{CurrentReplicas: 4}, start, start.Add(120 * time.Second), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &StepPolicy{
+				Bounds:        Bounds{Min: 1, Max: 6},
+				Step:          2,
+				UpThreshold:   3,
+				DownThreshold: 1,
+				UpCooldown:    30 * time.Second,
+				DownCooldown:  120 * time.Second,
+				lastChange:    tt.lastChange,
+			}
+			if got := p.Decide(tt.in, tt.now); got.Replicas != tt.want {
+				t.Errorf("Decide(%s) = %d replicas (%s), want %d", tt.in, got.Replicas, got.Reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestStepPolicyCooldownStartsWhenApplied(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	p := &StepPolicy{Bounds: Bounds{Min: 1, Max: 6}, Step: 1, UpThreshold: 3, DownThreshold: 1, UpCooldown: time.Minute, DownCooldown: time.Minute}
+	busy := Inputs{QueueLength: 10, CurrentReplicas: 2}
+
+	// The orchestrator failed to scale, the change is retried right away
+	if got := p.Decide(busy, start); got.Replicas != 3 {
+		t.Fatalf("Decide() = %d replicas, want 3", got.Replicas)
+	}
+	if got := p.Decide(busy, start.Add(5*time.Second)); got.Replicas != 3 {
+		t.Errorf("Decide() after a failed change = %d replicas (%s), want 3", got.Replicas, got.Reason)
+	}
+
+	// Once applied, the cooldown holds the next change
+	p.Applied(3, start.Add(5*time.Second))
+	busy.CurrentReplicas = 3
+	if got := p.Decide(busy, start.Add(10*time.Second)); got.Replicas != 3 {
+		t.Errorf("Decide() within the cooldown = %d replicas (%s), want 3", got.Replicas, got.Reason)
+	}
+	if got := p.Decide(busy, start.Add(65*time.Second)); got.Replicas != 4 {
+		t.Errorf("Decide() after the cooldown = %d replicas (%s), want 4", got.Replicas, got.Reason)
+	}
+}
@@ -0,0 +1,37 @@
+package scaling
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ThresholdPolicy asks for one replica per Threshold outstanding chunks.
+// Replicas are only removed once the outstanding chunks drop half a Threshold below what one replica less takes,
+// so a load hovering around a multiple of Threshold does not add and remove a replica at every decision.
+type ThresholdPolicy struct {
+	Bounds    Bounds
+	Threshold int // Chunks per worker before scaling up
+}
+
+func (p *ThresholdPolicy) Name() string {
+	return "threshold"
+}
+
+func (p *ThresholdPolicy) Decide(in Inputs, now time.Time) Decision {
+	outstanding := float64(in.Outstanding())
+	replicas := int(math.Ceil(outstanding / float64(p.Threshold)))
+	reason := fmt.Sprintf("%d outstanding chunks at %d per worker", in.Outstanding(), p.Threshold)
+	if replicas < in.CurrentReplicas && outstanding > (float64(in.CurrentReplicas-1)-0.5)*float64(p.Threshold) {
+		return Decision{
+			Replicas: p.Bounds.clamp(in.CurrentReplicas),
+			Reason:   reason + ", too close to scale down",
+		}
+	}
+	return Decision{
+		Replicas: p.Bounds.clamp(replicas),
+		Reason:   reason,
+	}
+}
+
+func (p *ThresholdPolicy) Applied(replicas int, now time.Time) {}
@@ -0,0 +1,51 @@
+package scaling
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThresholdPolicyDecide(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		in   Inputs
+		want int
+	}{
+		{"nothing outstanding", Inputs{CurrentReplicas: 1}, 1},
+		{"one per threshold", Inputs{QueueLength: 8, BusyWorkers: 1, CurrentReplicas: 1}, 3},
+		{"partial threshold rounds up", Inputs{QueueLength: 7, CurrentReplicas: 2}, 3},
+		{"clamped to max", Inputs{QueueLength: 100, CurrentReplicas: 3}, 5},
+		{"clamped to min", Inputs{CurrentReplicas: 0}, 1},
+		{"above max", Inputs{QueueLength: 30, CurrentReplicas: 8}, 5},
+
+		// With 4 replicas, the chunks must drop to 7 before removing one
+		{"just below current", Inputs{QueueLength: 9, CurrentReplicas: 4}, 4},
+		{"within half a threshold", Inputs{QueueLength: 8, CurrentReplicas: 4}, 4},
+		{"half a threshold below", Inputs{QueueLength: 7, CurrentReplicas: 4}, 3},
+		{"far below", Inputs{QueueLength: 2, CurrentReplicas: 4}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &ThresholdPolicy{Bounds: Bounds{Min: 1, Max: 5}, Threshold: 3}
+			if got := p.Decide(tt.in, now); got.Replicas != tt.want {
+				t.Errorf("Decide(%s) = %d replicas (%s), want %d", tt.in, got.Replicas, got.Reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestThresholdPolicyDoesNotFlap(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	p := &ThresholdPolicy{Bounds: Bounds{Min: 1, Max: 10}, Threshold: 3}
+
+	// The load hovers around 2 replicas worth of chunks
+	replicas := 2
+	for i, outstanding := range []int{7, 6, 7, 6, 5, 7, 6} {
+		got := p.Decide(Inputs{QueueLength: outstanding, CurrentReplicas: replicas}, now.Add(time.Duration(i)*5*time.Second))
+		if i > 0 && got.Replicas != 3 {
+			t.Errorf("decision %d with %d chunks = %d replicas (%s), want 3", i, outstanding, got.Replicas, got.Reason)
+		}
+		replicas = got.Replicas
+	}
+}
@@ -0,0 +1,43 @@
+package scaling
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ThroughputPolicy asks for enough replicas to search every outstanding chunk within DrainTime,
+// based on the throughput measured per busy replica.
+type ThroughputPolicy struct {
+	Bounds    Bounds
+	DrainTime time.Duration
+}
+
+func (p *ThroughputPolicy) Name() string {
+	return "throughput"
+}
+
+func (p *ThroughputPolicy) Decide(in Inputs, now time.Time) Decision {
+	outstanding := in.Outstanding()
+	if outstanding == 0 {
+		return Decision{Replicas: p.Bounds.Min, Reason: "no outstanding chunks"}
+	}
+
+	if in.Throughput == 0 || in.BusyWorkers == 0 {
+		// Nothing measured yet, make sure at least one replica gets to work
+		replicas := in.CurrentReplicas
+		if replicas == 0 {
+			replicas = 1
+		}
+		return Decision{Replicas: p.Bounds.clamp(replicas), Reason: "no throughput measured yet"}
+	}
+
+	perReplica := in.Throughput / float64(in.BusyWorkers)
+	replicas := int(math.Ceil(float64(outstanding) / (perReplica * p.DrainTime.Seconds())))
+	return Decision{
+		Replicas: p.Bounds.clamp(replicas),
+		Reason:   fmt.Sprintf("%d outstanding chunks at %.3f chunks/s per replica to drain in %s", outstanding, perReplica, p.DrainTime),
+	}
+}
+
+func (p *ThroughputPolicy) Applied(replicas int, now time.Time) {}
@@ -0,0 +1,35 @@
+package scaling
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThroughputPolicyDecide(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		in   Inputs
+		want int
+	}{
+		{"nothing outstanding", Inputs{CurrentReplicas: 4, Throughput: 2, BusyWorkers: 0}, 1},
+		{"nothing measured, no replica", Inputs{QueueLength: 10}, 1},
+		{"nothing measured, keeps replicas", Inputs{QueueLength: 10, CurrentReplicas: 3}, 3},
+		{"nothing measured, above max", Inputs{QueueLength: 10, CurrentReplicas: 12}, 8},
+		{"no busy worker", Inputs{QueueLength: 10, CurrentReplicas: 2, Throughput: 1}, 2},
+
+		// 0.1 chunk/s per replica drains 6 chunks a minute
+		{"drains in time", Inputs{QueueLength: 10, BusyWorkers: 2, CurrentReplicas: 2, Throughput: 0.2}, 2},
+		{"partial replica rounds up", Inputs{QueueLength: 11, BusyWorkers: 2, CurrentReplicas: 2, Throughput: 0.2}, 3},
+		{"scales down", Inputs{QueueLength: 2, BusyWorkers: 2, CurrentReplicas: 4, Throughput: 0.2}, 1},
+		{"clamped to max", Inputs{QueueLength: 500, BusyWorkers: 2, CurrentReplicas: 2, Throughput: 0.2}, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &ThroughputPolicy{Bounds: Bounds{Min: 1, Max: 8}, DrainTime: time.Minute}
+			if got := p.Decide(tt.in, now); got.Replicas != tt.want {
+				t.Errorf("Decide(%s) = %d replicas (%s), want %d", tt.in, got.Replicas, got.Reason, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Command worker is a reference worker speaking the slave protocol of TheLeadDestroyer.
+//
+// It connects to the coordinator, identifies as a slave along with its container ID and task slot, then brute-forces the hashes it is sent,
+// hashes the lines of a wordlist it fetches from the coordinator over HTTP, optionally mangled by a ruleset, or the words of a hashcat-style mask:
+//
+//	search <hash> <begin> <end> [charset=<characters>] [algo=<md5|sha1|sha256|sha512|ntlm>]
+//	wordlist <hash> <name> <first-line> <last-line> [algo=<md5|sha1|sha256|sha512|ntlm>] [rules=<name>]
+//	mask <hash> <mask> <first-index> <last-index> [algo=<md5|sha1|sha256|sha512|ntlm>] [1=<charset>] ... [4=<charset>]
+//
+// and answers "found <hash> <word>" when a word of the range matches, or "exhausted <hash> <first> <last>",
+// echoing the bounds of the range, once it was searched without a match. "stop" aborts the current search.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+func main() {
+	wsURL := flag.String("url", getEnvOrDefault("COORDINATOR_URL", "ws://127.0.0.1:8080/ws"), "WebSocket URL of the coordinator")
+	threads := flag.Int("threads", runtime.NumCPU(), "Number of goroutines searching a range")
+	id := flag.String("id", workerID(), "Container ID reported to the coordinator")
+	slot := flag.String("slot", os.Getenv("TASK_SLOT"), "Swarm task slot reported to the coordinator")
+	httpURL := flag.String("http", os.Getenv("COORDINATOR_HTTP_URL"), "HTTP URL of the coordinator, derived from the WebSocket URL by default")
+	flag.Parse()
+	if *threads < 1 {
+		log.Fatalf("Invalid number of threads %d, expected at least 1\n", *threads)
+	}
+	if flag.NArg() > 0 {
+		*wsURL = flag.Arg(0)
+	}
+
+	coordinator, err := coordinatorHTTPURL(*wsURL, *httpURL)
+	if err != nil {
+		log.Fatalf("Invalid coordinator URL: %v\n", err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(*wsURL, nil)
+	if err != nil {
+		log.Fatalf("Failed to connect to %s: %v\n", *wsURL, err)
+	}
+	defer conn.Close()
+
+	w := &worker{conn: conn, threads: *threads, coordinator: coordinator}
+	handshake := "slave container=" + *id
+	if *slot != "" {
+		handshake += " slot=" + *slot
+	}
+	if err := w.send(handshake); err != nil {
+		log.Fatalf("Failed to identify as slave: %v\n", err)
+	}
+	log.Printf("Connected to %s\n", *wsURL)
+
+	w.run()
+}
+
+// worker runs the searches requested by the coordinator, one at a time.
+type worker struct {
+	conn        *websocket.Conn
+	writeMu     sync.Mutex // A WebSocket connection supports a single writer at a time
+	threads     int
+	coordinator *url.URL           // HTTP URL of the coordinator, serving the wordlists and rulesets
+	cancel      context.CancelFunc // Aborts the current search, if any
+}
+
+// run reads the coordinator messages until the connection closes.
+func (w *worker) run() {
+	for {
+		_, message, err := w.conn.ReadMessage()
+		if err != nil {
+			w.stop()
+			log.Fatalf("Connection to coordinator lost: %v\n", err)
+		}
+
+		fields := strings.Fields(string(message))
+		if len(fields) == 0 {
+			continue
+		}
+
+		var t task
+		switch fields[0] {
+		case "search":
+			t, err = parseSearch(fields[1:])
+		case "wordlist":
+			t, err = parseWordlist(fields[1:], w.coordinator)
+		case "mask":
+			t, err = parseMask(fields[1:])
+
+		case "stop":
+			w.stop()
+
+		default:
+			log.Printf("Ignoring unknown message %q\n", message)
+		}
+		if t == nil {
+			continue
+		}
+		if err != nil {
+			log.Printf("Ignoring invalid %s %q: %v\n", fields[0], message, err)
+			continue
+		}
+
+		w.stop()
+		ctx, cancel := context.WithCancel(context.Background())
+		w.cancel = cancel
+		go w.search(ctx, t)
+	}
+}
+
+// stop aborts the current search.
+func (w *worker) stop() {
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+}
+
+// search hashes the candidates of a task and reports the matching word to the coordinator.
+func (w *worker) search(ctx context.Context, t task) {
+	hash := t.goal().hash
+	log.Printf("Searching %s in %s\n", hash, t)
+	word, ok, err := t.run(ctx, w.threads)
+	if ctx.Err() != nil {
+		log.Printf("Search of %s stopped\n", hash)
+		return
+	}
+	if err != nil {
+		// The protocol has no failure reply: disconnecting makes the coordinator hand the chunk to another worker
+		log.Printf("Search of %s failed, disconnecting: %v\n", hash, err)
+		w.conn.Close()
+		return
+	}
+	if !ok {
+		log.Printf("No match for %s in %s\n", hash, t)
+		begin, end := t.bounds()
+		if err := w.send("exhausted " + hash + " " + begin + " " + end); err != nil {
+			log.Printf("Failed to report exhausted range: %v\n", err)
+		}
+		return
+	}
+
+	log.Printf("Found %s: %s\n", hash, word)
+	if err := w.send("found " + hash + " " + word); err != nil {
+		log.Printf("Failed to send solution: %v\n", err)
+	}
+}
+
+// send writes a text message to the coordinator.
+func (w *worker) send(message string) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	return w.conn.WriteMessage(websocket.TextMessage, []byte(message))
+}
+
+// coordinatorHTTPURL returns the HTTP URL of the coordinator: the given one, or the WebSocket URL
+// with an http(s) scheme and without its /ws path.
+func coordinatorHTTPURL(wsURL, httpURL string) (*url.URL, error) {
+	if httpURL != "" {
+		return url.Parse(httpURL)
+	}
+	u, err := url.Parse(wsURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/ws")
+	return u, nil
+}
+
+// workerID returns the ID given by the orchestrator, or the hostname which is the short container ID under Docker.
+func workerID() string {
+	if id := os.Getenv("WORKER_ID"); id != "" {
+		return id
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return hostname
+}
+
+// getEnvOrDefault returns the value of an environment variable, or a default value when it is unset.
+func getEnvOrDefault(key, defaultValue string) string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/mask"
+)
+
+// maskRequest is a range of the words of a mask to brute-force for a hash.
+type maskRequest struct {
+	target
+	mask   mask.Mask
+	source string // Mask as received, for the logs
+	first  uint64
+	last   uint64
+}
+
+// parseMask reads the arguments of a mask message: <hash> <mask> <first-index> <last-index> [algo=<name>] [1=<charset>] ... [4=<charset>].
+func parseMask(args []string) (maskRequest, error) {
+	if len(args) < 4 {
+		return maskRequest{}, fmt.Errorf("expected <hash> <mask> <first-index> <last-index>, got %d arguments", len(args))
+	}
+
+	req := maskRequest{source: args[1]}
+	var err error
+	if req.first, err = strconv.ParseUint(args[2], 10, 64); err != nil {
+		return req, fmt.Errorf("invalid first index %q", args[2])
+	}
+	if req.last, err = strconv.ParseUint(args[3], 10, 64); err != nil {
+		return req, fmt.Errorf("invalid last index %q", args[3])
+	}
+	if req.last < req.first {
+		return req, fmt.Errorf("range %d..%d is empty", req.first, req.last)
+	}
+
+	algorithm := ""
+	var custom []string
+	for _, option := range args[4:] {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "algo":
+			algorithm = value
+		case "1", "2", "3", "4":
+			index, _ := strconv.Atoi(key)
+			for len(custom) < index {
+				custom = append(custom, "")
+			}
+			custom[index-1] = value
+		default:
+			return req, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	if req.target, err = parseTarget(args[0], algorithm); err != nil {
+		return req, err
+	}
+
+	if req.mask, err = mask.Parse(args[1], custom); err != nil {
+		return req, err
+	}
+	size, err := req.mask.Size()
+	if err != nil {
+		return req, err
+	}
+	if req.last >= size {
+		return req, fmt.Errorf("index %d is out of the %d words of the mask", req.last, size)
+	}
+	return req, nil
+}
+
+func (req maskRequest) bounds() (string, string) {
+	return strconv.FormatUint(req.first, 10), strconv.FormatUint(req.last, 10)
+}
+
+func (req maskRequest) String() string {
+	return fmt.Sprintf("words %d to %d of mask %s", req.first, req.last, req.source)
+}
+
+// run splits the range between threads and returns the first matching word.
+func (req maskRequest) run(ctx context.Context, threads int) (string, bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	total := req.last - req.first + 1
+	if uint64(threads) > total {
+		threads = int(total)
+	}
+	share := total / uint64(threads)
+
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		found string
+		ok    bool
+	)
+	for t := 0; t < threads; t++ {
+		from := req.first + uint64(t)*share
+		to := from + share - 1
+		if t == threads-1 {
+			to = req.last
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if word, match := req.scan(ctx, from, to); match {
+				once.Do(func() {
+					found, ok = word, true
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	return found, ok, nil
+}
+
+// scan hashes every word of the mask from index from to index to, both included.
+func (req maskRequest) scan(ctx context.Context, from, to uint64) (string, bool) {
+	hash := req.algorithm.Hasher()
+	digits := req.mask.Digits(from)
+	word := []byte(req.mask.Word(from))
+
+	for index := from; ; index++ {
+		if (index-from)%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return "", false
+		}
+
+		if bytes.Equal(hash(word), req.digest) {
+			return string(word), true
+		}
+		if index == to {
+			return "", false
+		}
+		req.mask.Next(word, digits)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/hashing"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/keyspace"
+)
+
+// cancelCheckInterval is the number of candidates tried between two checks for cancellation.
+const cancelCheckInterval = 4096
+
+// task is a chunk of candidates to hash, looking for a target.
+type task interface {
+	fmt.Stringer // Describes the chunk, for the logs
+	goal() target
+	bounds() (string, string) // First and last candidates as sent by the coordinator, echoed when exhausted
+	run(ctx context.Context, threads int) (string, bool, error)
+}
+
+// target is the hash a task looks for.
+type target struct {
+	hash      string
+	algorithm hashing.Algorithm
+	digest    []byte
+}
+
+// parseTarget reads a hexadecimal hash of the named algorithm, MD5 when the name is empty.
+func parseTarget(hash, algorithm string) (target, error) {
+	t := target{hash: hash}
+	var err error
+	if t.algorithm, err = hashing.Lookup(algorithm); err != nil {
+		return t, err
+	}
+	if err := t.algorithm.Validate(hash); err != nil {
+		return t, err
+	}
+	t.digest, _ = hex.DecodeString(hash)
+	return t, nil
+}
+
+func (t target) goal() target {
+	return t
+}
+
+// searchRequest is a range of the keyspace to brute-force for a hash.
+type searchRequest struct {
+	target
+	begin    string
+	end      string
+	keyspace keyspace.Keyspace
+	first    uint64
+	last     uint64
+}
+
+// parseSearch reads the arguments of a search message: <hash> <begin> <end> [charset=<characters>] [algo=<name>].
+func parseSearch(args []string) (searchRequest, error) {
+	if len(args) < 3 {
+		return searchRequest{}, fmt.Errorf("expected <hash> <begin> <end>, got %d arguments", len(args))
+	}
+
+	req := searchRequest{
+		begin: args[1],
+		end:   args[2],
+		keyspace: keyspace.Keyspace{
+			Charset:   keyspace.DefaultCharset,
+			MinLength: len(args[1]),
+			MaxLength: len(args[2]),
+		},
+	}
+	algorithm := ""
+	for _, option := range args[3:] {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "charset":
+			req.keyspace.Charset = value
+		case "algo":
+			algorithm = value
+		default:
+			return req, fmt.Errorf("unknown option %q", key)
+		}
+	}
+
+	var err error
+	if req.target, err = parseTarget(args[0], algorithm); err != nil {
+		return req, err
+	}
+
+	if err := req.keyspace.Validate(); err != nil {
+		return req, err
+	}
+	if req.first, err = req.keyspace.Index(req.begin); err != nil {
+		return req, err
+	}
+	if req.last, err = req.keyspace.Index(req.end); err != nil {
+		return req, err
+	}
+	if req.last < req.first {
+		return req, fmt.Errorf("range %s..%s is empty", req.begin, req.end)
+	}
+	return req, nil
+}
+
+func (req searchRequest) bounds() (string, string) {
+	return req.begin, req.end
+}
+
+func (req searchRequest) String() string {
+	return fmt.Sprintf("%s to %s", req.begin, req.end)
+}
+
+// run splits the range between threads and returns the first matching word.
+func (req searchRequest) run(ctx context.Context, threads int) (string, bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	total := req.last - req.first + 1
+	if uint64(threads) > total {
+		threads = int(total)
+	}
+	share := total / uint64(threads)
+
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		found string
+	)
+	for t := 0; t < threads; t++ {
+		from := req.first + uint64(t)*share
+		to := from + share - 1
+		if t == threads-1 {
+			to = req.last
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if word, ok := req.scan(ctx, from, to); ok {
+				once.Do(func() {
+					found = word
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	return found, found != "", nil
+}
+
+// scan hashes every word from index from to index to, both included.
+func (req searchRequest) scan(ctx context.Context, from, to uint64) (string, bool) {
+	charset := req.keyspace.Charset
+	hash := req.algorithm.Hasher()
+	word := []byte(req.keyspace.Word(from))
+	digits := make([]int, len(word))
+	for i := range word {
+		digits[i] = strings.IndexByte(charset, word[i])
+	}
+
+	for index := from; ; index++ {
+		if (index-from)%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return "", false
+		}
+
+		if bytes.Equal(hash(word), req.digest) {
+			return string(word), true
+		}
+		if index == to {
+			return "", false
+		}
+		word, digits = increment(word, digits, charset)
+	}
+}
+
+// increment moves a word to the next one of the keyspace, growing it once every character wrapped around.
+func increment(word []byte, digits []int, charset string) ([]byte, []int) {
+	for i := len(word) - 1; i >= 0; i-- {
+		digits[i]++
+		if digits[i] < len(charset) {
+			word[i] = charset[digits[i]]
+			return word, digits
+		}
+		digits[i] = 0
+		word[i] = charset[0]
+	}
+
+	// Every word of this length was tried, continue with the first word one character longer
+	word = append(word, charset[0])
+	digits = append(digits, 0)
+	return word, digits
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/rules"
+)
+
+// rulesets caches the parsed rulesets by name, as an uploaded ruleset never changes.
+var rulesets sync.Map
+
+// wordlistRequest is a range of lines of a wordlist uploaded to the coordinator, to hash for a hash.
+type wordlistRequest struct {
+	target
+	name    string
+	first   uint64
+	last    uint64
+	source  *url.URL // Where the lines are fetched from
+	rules   string   // Ruleset mangling every line, if any
+	ruleset *url.URL // Where the ruleset is fetched from
+}
+
+// parseWordlist reads the arguments of a wordlist message: <hash> <name> <first-line> <last-line> [algo=<name>] [rules=<name>].
+// The lines, and the ruleset, are fetched from the coordinator whose HTTP URL is given.
+func parseWordlist(args []string, coordinator *url.URL) (wordlistRequest, error) {
+	if len(args) < 4 {
+		return wordlistRequest{}, fmt.Errorf("expected <hash> <name> <first-line> <last-line>, got %d arguments", len(args))
+	}
+
+	req := wordlistRequest{name: args[1]}
+	var err error
+	if req.first, err = strconv.ParseUint(args[2], 10, 64); err != nil {
+		return req, fmt.Errorf("invalid first line %q", args[2])
+	}
+	if req.last, err = strconv.ParseUint(args[3], 10, 64); err != nil {
+		return req, fmt.Errorf("invalid last line %q", args[3])
+	}
+	if req.last < req.first {
+		return req, fmt.Errorf("lines %d to %d are empty", req.first, req.last)
+	}
+
+	algorithm := ""
+	for _, option := range args[4:] {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "algo":
+			algorithm = value
+		case "rules":
+			req.rules = value
+			req.ruleset = coordinator.JoinPath("rules", value, "content")
+		default:
+			return req, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	if req.target, err = parseTarget(args[0], algorithm); err != nil {
+		return req, err
+	}
+
+	query := url.Values{}
+	query.Set("first", args[2])
+	query.Set("last", args[3])
+	req.source = coordinator.JoinPath("wordlists", req.name, "lines")
+	req.source.RawQuery = query.Encode()
+	return req, nil
+}
+
+func (req wordlistRequest) bounds() (string, string) {
+	return strconv.FormatUint(req.first, 10), strconv.FormatUint(req.last, 10)
+}
+
+func (req wordlistRequest) String() string {
+	if req.rules != "" {
+		return fmt.Sprintf("lines %d to %d of wordlist %s with ruleset %s", req.first, req.last, req.name, req.rules)
+	}
+	return fmt.Sprintf("lines %d to %d of wordlist %s", req.first, req.last, req.name)
+}
+
+// run fetches the lines and splits them between threads, returning the first matching word.
+func (req wordlistRequest) run(ctx context.Context, threads int) (string, bool, error) {
+	words, err := req.fetch(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	var ruleset []rules.Rule
+	if req.rules != "" {
+		if ruleset, err = req.fetchRules(ctx); err != nil {
+			return "", false, err
+		}
+	}
+	word, ok := matchWords(ctx, req.target, words, ruleset, threads)
+	return word, ok, nil
+}
+
+// fetchRules downloads and parses the ruleset of the request, unless it was already.
+func (req wordlistRequest) fetchRules(ctx context.Context) ([]rules.Rule, error) {
+	if cached, ok := rulesets.Load(req.rules); ok {
+		return cached.([]rules.Rule), nil
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.ruleset.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ruleset %s: %v", req.rules, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch ruleset %s: %s", req.rules, resp.Status)
+	}
+
+	parsed, err := rules.ParseFile(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ruleset %s: %v", req.rules, err)
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("ruleset %s has no rules", req.rules)
+	}
+	rulesets.Store(req.rules, parsed)
+	return parsed, nil
+}
+
+// fetch downloads the lines of the request from the coordinator.
+func (req wordlistRequest) fetch(ctx context.Context) ([][]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.source.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %v", req, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", req, resp.Status)
+	}
+
+	expected := req.last - req.first + 1
+	words := make([][]byte, 0, expected)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		words = append(words, bytes.Clone(bytes.TrimSuffix(scanner.Bytes(), []byte("\r"))))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", req, err)
+	}
+	// The coordinator cannot change the status once it started sending lines
+	if uint64(len(words)) != expected {
+		return nil, fmt.Errorf("received %d of the %d %s", len(words), expected, req)
+	}
+	return words, nil
+}
+
+// matchWords splits words between threads and returns the first one hashing to the target.
+// With a ruleset, every word is mangled by each rule and the first matching candidate is returned instead.
+func matchWords(ctx context.Context, t target, words [][]byte, ruleset []rules.Rule, threads int) (string, bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if threads > len(words) {
+		threads = len(words)
+	}
+	share := len(words) / threads
+
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		found string
+		ok    bool
+	)
+	for th := 0; th < threads; th++ {
+		from := th * share
+		to := from + share
+		if th == threads-1 {
+			to = len(words)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hash := t.algorithm.Hasher()
+			for i := from; i < to; i++ {
+				if (i-from)%cancelCheckInterval == 0 && ctx.Err() != nil {
+					return
+				}
+				if candidate, match := matchWord(hash, t.digest, words[i], ruleset); match {
+					once.Do(func() {
+						found, ok = string(candidate), true
+						cancel()
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return found, ok
+}
+
+// matchWord returns the candidate derived from a word that hashes to the digest: the word itself without a ruleset,
+// or the word mangled by one of the rules.
+func matchWord(hash func([]byte) []byte, digest, word []byte, ruleset []rules.Rule) ([]byte, bool) {
+	if len(ruleset) == 0 {
+		return word, bytes.Equal(hash(word), digest)
+	}
+	for _, rule := range ruleset {
+		if candidate := rule.Apply(word); bytes.Equal(hash(candidate), digest) {
+			return candidate, true
+		}
+	}
+	return nil, false
+}
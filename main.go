@@ -2,16 +2,42 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/cache"
 	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/docker"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/process"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/storage"
 	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/adapters/websocket_adapter"
 	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/handlers"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/scaling"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/ports"
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/tracing"
+
+	"github.com/docker/go-units"
 )
 
+// shutdowner is implemented by orchestrators whose workers must be stopped along with the coordinator.
+type shutdowner interface {
+	Shutdown()
+}
+
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v\n", err)
+	}
+	defer shutdownTracing(context.Background())
 	//err := godotenv.Load(".env")
 	//if err != nil {
 	//	log.Fatal("Error loading .env file")
@@ -25,24 +51,279 @@ func main() {
 		log.Fatal("Please make sure env variables are integers.")
 	}
 
-	containerWSAdapter := websocket_adapter.NewContainerWebSocketAdapter()
+	chunkSize, err := strconv.ParseUint(getEnvOrDefault("CHUNK_SIZE", "1000000"), 10, 64)
+	if err != nil || chunkSize == 0 {
+		log.Fatal("Please make sure CHUNK_SIZE is a positive integer.")
+	}
+
+	store, err := newJobStore(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v\n", err)
+	}
+	wordlists, err := storage.NewFileWordlistStore(getEnvOrDefault("WORDLIST_DIR", "wordlists"))
+	if err != nil {
+		log.Fatalf("Failed to initialize wordlist storage: %v\n", err)
+	}
+	rulesets, err := storage.NewFileRulesetStore(getEnvOrDefault("RULES_DIR", "rules"))
+	if err != nil {
+		log.Fatalf("Failed to initialize ruleset storage: %v\n", err)
+	}
+	maxWordlistSize, err := units.RAMInBytes(getEnvOrDefault("WORDLIST_MAX_SIZE", "1g"))
+	if err != nil || maxWordlistSize <= 0 {
+		log.Fatal("Please make sure WORDLIST_MAX_SIZE is a positive size, such as 512m.")
+	}
+
+	cacheSize, err := strconv.Atoi(getEnvOrDefault("CACHE_SIZE", "10000"))
+	if err != nil {
+		log.Fatal("Please make sure CACHE_SIZE is an integer.")
+	}
+	solutionCache, err := cache.NewLRUCache(cacheSize, os.Getenv("CACHE_FILE"))
+	if err != nil {
+		log.Fatalf("Failed to initialize solution cache: %v\n", err)
+	}
+
+	heartbeatInterval, err := strconv.Atoi(getEnvOrDefault("HEARTBEAT_INTERVAL", "10"))
+	if err != nil || heartbeatInterval <= 0 {
+		log.Fatal("Please make sure HEARTBEAT_INTERVAL is a positive integer.")
+	}
+	workerTimeout, err := strconv.Atoi(getEnvOrDefault("WORKER_TIMEOUT", "30"))
+	if err != nil || workerTimeout <= heartbeatInterval {
+		log.Fatal("Please make sure WORKER_TIMEOUT is an integer greater than HEARTBEAT_INTERVAL.")
+	}
+
+	containerWSAdapter := websocket_adapter.NewContainerWebSocketAdapter(time.Duration(heartbeatInterval)*time.Second, time.Duration(workerTimeout)*time.Second)
+	registry := jobs.NewRegistry(store)
+	jobRetention, err := strconv.Atoi(getEnvOrDefault("JOB_RETENTION", "86400"))
+	if err != nil || jobRetention <= 0 {
+		log.Fatal("Please make sure JOB_RETENTION is a positive integer.")
+	}
 
 	// Initialize TaskDistributor
-	swarmAdapter, err := docker.New("md5onelettertest")
+	orchestrator, err := newOrchestrator()
 	if err != nil {
 		panic(err)
 	}
-	taskDistributor := handlers.NewDistributor(containerWSAdapter, swarmAdapter, minReplicas, maxReplicas, threshold)
+	go func() {
+		<-ctx.Done()
+		log.Println("Coordinator shutting down")
+		if s, ok := orchestrator.(shutdowner); ok {
+			s.Shutdown()
+		}
+		shutdownTracing(context.Background())
+		os.Exit(0)
+	}()
+	policy, err := newScalingPolicy(scaling.Bounds{Min: minReplicas, Max: maxReplicas}, threshold)
+	if err != nil {
+		log.Fatalf("Failed to initialize scaling policy: %v\n", err)
+	}
+	taskDistributor := handlers.NewDistributor(containerWSAdapter, orchestrator, registry, store, wordlists, rulesets, policy, chunkSize)
+	if err := taskDistributor.Recover(ctx); err != nil {
+		log.Fatalf("Failed to recover jobs: %v\n", err)
+	}
 	go taskDistributor.Start(ctx)
+	go registry.StartExpiry(ctx, time.Duration(jobRetention)*time.Second)
+
+	workerRegistry := handlers.NewWorkerRegistry(orchestrator)
+	go workerRegistry.Start(ctx)
 
 	// Initialize SolutionReceiver
-	resultChannel := make(chan string, 100)
-	solutionReceiver := handlers.NewSolutionReceiver(containerWSAdapter, resultChannel, taskDistributor)
+	router := handlers.NewResultRouter()
+	solutionReceiver := handlers.NewSolutionReceiver(containerWSAdapter, router, taskDistributor, registry, store, solutionCache)
 	go solutionReceiver.Start()
 
 	// Initialize ConnectionFactory
-	connectionFactory := handlers.NewConnectionFactory(containerWSAdapter, taskDistributor, resultChannel)
+	maxQueuedJobs, err := strconv.Atoi(getEnvOrDefault("MAX_QUEUED_JOBS", "100"))
+	if err != nil || maxQueuedJobs <= 0 {
+		log.Fatal("Please make sure MAX_QUEUED_JOBS is a positive integer.")
+	}
+	retryAfter, err := strconv.Atoi(getEnvOrDefault("RETRY_AFTER", "10"))
+	if err != nil || retryAfter <= 0 {
+		log.Fatal("Please make sure RETRY_AFTER is a positive integer.")
+	}
+	jobService := handlers.NewJobService(taskDistributor, registry, router, solutionCache, store, maxQueuedJobs, time.Duration(retryAfter)*time.Second)
+	connectionFactory := handlers.NewConnectionFactory(containerWSAdapter, taskDistributor, jobService, router, solutionCache, workerRegistry, wordlists, rulesets, maxWordlistSize)
 
 	// Start the WebSocket server
 	connectionFactory.StartServer("8080")
 }
+
+// newOrchestrator creates the worker orchestrator selected by the ORCHESTRATOR environment variable (swarm or process).
+func newOrchestrator() (ports.WorkerOrchestrator, error) {
+	switch backend := getEnvOrDefault("ORCHESTRATOR", "swarm"); backend {
+	case "swarm":
+		config, err := workerServiceConfig()
+		if err != nil {
+			return nil, err
+		}
+		return docker.New(config)
+	case "process":
+		binary := os.Getenv("WORKER_BINARY")
+		if binary == "" {
+			return nil, fmt.Errorf("WORKER_BINARY is required when ORCHESTRATOR=process")
+		}
+		stopTimeout, err := strconv.Atoi(getEnvOrDefault("CONTAINER_TIMEOUT", "10"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONTAINER_TIMEOUT value: %v", err)
+		}
+		args := strings.Fields(os.Getenv("WORKER_ARGS"))
+		coordinatorURL := getEnvOrDefault("COORDINATOR_URL", "ws://127.0.0.1:8080/ws")
+		return process.New(binary, args, coordinatorURL, time.Duration(stopTimeout)*time.Second)
+	default:
+		return nil, fmt.Errorf("unknown ORCHESTRATOR %q, expected swarm or process", backend)
+	}
+}
+
+// workerServiceConfig reads the spec of the Swarm worker service from the WORKER_* environment variables.
+// Lists are comma separated, except the command and arguments which are space separated like WORKER_ARGS.
+func workerServiceConfig() (docker.ServiceConfig, error) {
+	config := docker.DefaultServiceConfig()
+	config.Name = getEnvOrDefault("WORKER_SERVICE_NAME", config.Name)
+	config.Image = getEnvOrDefault("WORKER_IMAGE", config.Image)
+	config.CoordinatorURL = getEnvOrDefault("COORDINATOR_URL", config.CoordinatorURL)
+	if command, ok := os.LookupEnv("WORKER_COMMAND"); ok {
+		config.Command = strings.Fields(command)
+	}
+	if args, ok := os.LookupEnv("WORKER_ARGS"); ok {
+		config.Args = strings.Fields(args)
+	}
+	if networks, ok := os.LookupEnv("WORKER_NETWORKS"); ok {
+		config.Networks = splitList(networks)
+	}
+	config.Env = splitList(os.Getenv("WORKER_ENV"))
+	config.Constraints = splitList(os.Getenv("WORKER_CONSTRAINTS"))
+
+	labels := splitList(os.Getenv("WORKER_LABELS"))
+	if len(labels) > 0 {
+		config.Labels = make(map[string]string, len(labels))
+	}
+	for _, label := range labels {
+		key, value, _ := strings.Cut(label, "=")
+		config.Labels[key] = value
+	}
+
+	var err error
+	if config.CPULimit, err = parseCPUs("WORKER_CPU_LIMIT"); err != nil {
+		return config, err
+	}
+	if config.CPUReservation, err = parseCPUs("WORKER_CPU_RESERVATION"); err != nil {
+		return config, err
+	}
+	if config.MemoryLimit, err = parseMemory("WORKER_MEMORY_LIMIT"); err != nil {
+		return config, err
+	}
+	if config.MemoryReservation, err = parseMemory("WORKER_MEMORY_RESERVATION"); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// splitList splits a comma separated list, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseCPUs reads a number of CPUs, such as 0.5, from an environment variable and returns it in billionths of a CPU.
+func parseCPUs(key string) (int64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0, nil
+	}
+	cpus, err := strconv.ParseFloat(value, 64)
+	if err != nil || cpus < 0 {
+		return 0, fmt.Errorf("invalid %s %q, expected a number of CPUs", key, value)
+	}
+	return int64(cpus * 1e9), nil
+}
+
+// parseMemory reads an amount of memory, such as 512m or 1g, from an environment variable and returns it in bytes.
+func parseMemory(key string) (int64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0, nil
+	}
+	bytes, err := units.RAMInBytes(value)
+	if err != nil || bytes < 0 {
+		return 0, fmt.Errorf("invalid %s %q, expected an amount of memory such as 512m", key, value)
+	}
+	return bytes, nil
+}
+
+// newScalingPolicy creates the scaling policy selected by the SCALING_POLICY environment variable (threshold, step or throughput).
+func newScalingPolicy(bounds scaling.Bounds, threshold int) (scaling.Policy, error) {
+	switch name := getEnvOrDefault("SCALING_POLICY", "threshold"); name {
+	case "threshold":
+		if threshold <= 0 {
+			return nil, fmt.Errorf("THRESHOLD must be positive")
+		}
+		return &scaling.ThresholdPolicy{Bounds: bounds, Threshold: threshold}, nil
+
+	case "step":
+		step, err := strconv.Atoi(getEnvOrDefault("SCALE_STEP", "1"))
+		if err != nil || step <= 0 {
+			return nil, fmt.Errorf("SCALE_STEP must be a positive integer")
+		}
+		up, err := strconv.ParseFloat(getEnvOrDefault("SCALE_UP_THRESHOLD", strconv.Itoa(threshold)), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCALE_UP_THRESHOLD: %v", err)
+		}
+		down, err := strconv.ParseFloat(getEnvOrDefault("SCALE_DOWN_THRESHOLD", "1"), 64)
+		if err != nil || down >= up {
+			return nil, fmt.Errorf("SCALE_DOWN_THRESHOLD must be a number lower than SCALE_UP_THRESHOLD")
+		}
+		upCooldown, err := strconv.Atoi(getEnvOrDefault("SCALE_UP_COOLDOWN", "30"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCALE_UP_COOLDOWN: %v", err)
+		}
+		downCooldown, err := strconv.Atoi(getEnvOrDefault("SCALE_DOWN_COOLDOWN", "120"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCALE_DOWN_COOLDOWN: %v", err)
+		}
+		return &scaling.StepPolicy{
+			Bounds:        bounds,
+			Step:          step,
+			UpThreshold:   up,
+			DownThreshold: down,
+			UpCooldown:    time.Duration(upCooldown) * time.Second,
+			DownCooldown:  time.Duration(downCooldown) * time.Second,
+		}, nil
+
+	case "throughput":
+		drainTime, err := strconv.Atoi(getEnvOrDefault("TARGET_DRAIN_TIME", "60"))
+		if err != nil || drainTime <= 0 {
+			return nil, fmt.Errorf("TARGET_DRAIN_TIME must be a positive integer")
+		}
+		return &scaling.ThroughputPolicy{Bounds: bounds, DrainTime: time.Duration(drainTime) * time.Second}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown SCALING_POLICY %q, expected threshold, step or throughput", name)
+	}
+}
+
+// newJobStore creates the job storage selected by the STORAGE environment variable (memory or redis).
+func newJobStore(ctx context.Context) (ports.JobStore, error) {
+	switch backend := getEnvOrDefault("STORAGE", "memory"); backend {
+	case "memory":
+		return storage.NewMemoryStore(), nil
+	case "redis":
+		addr := getEnvOrDefault("REDIS_HOST", "localhost") + ":" + getEnvOrDefault("REDIS_PORT", "6379")
+		log.Printf("Storing jobs in Redis at %s\n", addr)
+		return storage.NewRedisStore(ctx, addr, os.Getenv("REDIS_PASSWORD"), getEnvOrDefault("REDIS_PREFIX", "theleaddestroyer"))
+	default:
+		return nil, fmt.Errorf("unknown STORAGE %q, expected memory or redis", backend)
+	}
+}
+
+// getEnvOrDefault returns the value of an environment variable, or a default value when it is unset.
+func getEnvOrDefault(key, defaultValue string) string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	return value
+}
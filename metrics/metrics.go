@@ -0,0 +1,117 @@
+// Package metrics declares the Prometheus metrics exposed by the coordinator on /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "tld"
+
+var (
+	// QueuedChunks is the number of chunks waiting for a worker.
+	QueuedChunks = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queued_chunks",
+		Help:      "Chunks waiting to be handed to a worker.",
+	})
+
+	// QueuedSearches is the number of jobs with chunks left to hand out.
+	QueuedSearches = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queued_searches",
+		Help:      "Jobs with chunks left to hand out.",
+	})
+
+	// Jobs is the number of jobs known to the coordinator, by state.
+	Jobs = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "jobs",
+		Help:      "Jobs known to the coordinator, by state.",
+	}, []string{"state"})
+
+	// ConnectedWorkers is the number of open worker WebSocket connections.
+	ConnectedWorkers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "connected_workers",
+		Help:      "Workers connected over WebSocket.",
+	})
+
+	// Workers is the number of workers known to the distributor, by state (idle or busy).
+	Workers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workers",
+		Help:      "Workers known to the task distributor, by state.",
+	}, []string{"state"})
+
+	// CurrentReplicas is the number of replicas requested from the orchestrator.
+	CurrentReplicas = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "replicas_current",
+		Help:      "Worker replicas currently requested from the orchestrator.",
+	})
+
+	// DesiredReplicas is the number of replicas wanted by the scaling policy.
+	DesiredReplicas = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "replicas_desired",
+		Help:      "Worker replicas wanted by the scaling policy.",
+	})
+
+	// ScalingEvents counts the replica changes applied, by direction (up or down) and outcome (ok or error).
+	ScalingEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scaling_events_total",
+		Help:      "Replica changes applied to the orchestrator, by direction and outcome.",
+	}, []string{"direction", "outcome"})
+
+	// ChunksCompleted counts the chunks searched without finding the solution.
+	ChunksCompleted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chunks_completed_total",
+		Help:      "Chunks searched by the workers without finding the solution.",
+	})
+
+	// SolveDuration measures the time from submission to completion of the jobs, by final state.
+	SolveDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "solve_duration_seconds",
+		Help:      "Time from submission to completion of the jobs, by final state.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 4, 10), // 0.1s to about 7h
+	}, []string{"state"})
+
+	// WorkerMessages counts the messages exchanged with the workers, by direction (in or out).
+	WorkerMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "worker_messages_total",
+		Help:      "Messages exchanged with the workers, by direction.",
+	}, []string{"direction"})
+
+	// DroppedMessages counts the messages discarded, by reason.
+	DroppedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dropped_messages_total",
+		Help:      "Messages discarded by the coordinator, by reason.",
+	}, []string{"reason"})
+
+	// RejectedSubmissions counts the submissions refused, by reason (invalid, ambiguous or busy).
+	RejectedSubmissions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rejected_submissions_total",
+		Help:      "Job submissions refused, by reason.",
+	}, []string{"reason"})
+
+	// OrchestratorUp is 1 while the orchestrator is reachable, 0 otherwise.
+	OrchestratorUp = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "orchestrator_up",
+		Help:      "Whether the worker orchestrator was reachable on the last call.",
+	})
+
+	// DockerAPIErrors counts the failed Docker API calls, by operation.
+	DockerAPIErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "docker_api_errors_total",
+		Help:      "Failed Docker API calls, by operation.",
+	}, []string{"operation"})
+)
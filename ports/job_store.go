@@ -0,0 +1,46 @@
+package ports
+
+import (
+	"context"
+
+	"www-apps.univ-lehavre.fr/forge/themd5destroyers/theleaddestroyer/application/jobs"
+)
+
+// Assignment is a chunk of a job held by a worker.
+type Assignment struct {
+	JobID string `json:"jobId"`
+	Chunk uint64 `json:"chunk"`
+}
+
+// JobStore defines the interface for persisting jobs, in-flight assignments and solved results.
+type JobStore interface {
+	// SaveJob creates or replaces a job.
+	SaveJob(ctx context.Context, job jobs.Job) error
+
+	// LoadJobs returns every saved job.
+	LoadJobs(ctx context.Context) ([]jobs.Job, error)
+
+	// DeleteJobs forgets the jobs with the given IDs.
+	DeleteJobs(ctx context.Context, ids ...string) error
+
+	// SaveAssignment records the chunk currently held by a worker.
+	SaveAssignment(ctx context.Context, workerID string, assignment Assignment) error
+
+	// DeleteAssignment forgets the chunk held by a worker.
+	DeleteAssignment(ctx context.Context, workerID string) error
+
+	// LoadAssignments returns every saved assignment by worker ID.
+	LoadAssignments(ctx context.Context) (map[string]Assignment, error)
+
+	// SaveResult records the plaintext of a solved hash.
+	SaveResult(ctx context.Context, hash, plaintext string) error
+
+	// GetResult returns the plaintext of a solved hash, if known.
+	GetResult(ctx context.Context, hash string) (string, bool, error)
+
+	// DeleteResult forgets the plaintext of a solved hash and reports whether it was known.
+	DeleteResult(ctx context.Context, hash string) (bool, error)
+
+	// PurgeResults forgets every solved hash.
+	PurgeResults(ctx context.Context) error
+}
@@ -0,0 +1,34 @@
+package ports
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrRulesetExists is returned when uploading a ruleset under a name already taken.
+var ErrRulesetExists = errors.New("ruleset already exists")
+
+// ErrRulesetNotFound is returned when reading a ruleset that was never uploaded.
+var ErrRulesetNotFound = errors.New("ruleset not found")
+
+// Ruleset describes an uploaded ruleset of word-mangling rules.
+type Ruleset struct {
+	Name  string `json:"name"`
+	Rules int    `json:"rules"` // Comments and empty lines left out
+	Size  int64  `json:"size"`  // In bytes
+}
+
+// RulesetStore defines the interface for keeping the rulesets uploaded to the coordinator.
+type RulesetStore interface {
+	// Save stores a new ruleset read from content, rejecting it when a rule is invalid.
+	Save(name string, content io.Reader) (Ruleset, error)
+
+	// Get returns the description of a ruleset.
+	Get(name string) (Ruleset, bool)
+
+	// List returns every ruleset, sorted by name.
+	List() []Ruleset
+
+	// Read writes the content of a ruleset as uploaded.
+	Read(name string, w io.Writer) error
+}
@@ -0,0 +1,25 @@
+package ports
+
+// CachedSolution is a hash along with the plaintext it was cracked to.
+type CachedSolution struct {
+	Hash      string `json:"hash"`
+	Plaintext string `json:"plaintext"`
+}
+
+// SolutionCache defines the interface for remembering previously cracked hashes.
+type SolutionCache interface {
+	// Get returns the plaintext of a cracked hash, if cached.
+	Get(hash string) (string, bool)
+
+	// Put remembers the plaintext of a cracked hash.
+	Put(hash, plaintext string)
+
+	// List returns every cached solution, most recently used first.
+	List() []CachedSolution
+
+	// Delete forgets a single hash and reports whether it was cached.
+	Delete(hash string) bool
+
+	// Purge forgets every hash.
+	Purge()
+}
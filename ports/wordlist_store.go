@@ -0,0 +1,35 @@
+package ports
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrWordlistExists is returned when uploading a wordlist under a name already taken.
+var ErrWordlistExists = errors.New("wordlist already exists")
+
+// ErrWordlistNotFound is returned when reading a wordlist that was never uploaded.
+var ErrWordlistNotFound = errors.New("wordlist not found")
+
+// Wordlist describes an uploaded wordlist.
+type Wordlist struct {
+	Name  string `json:"name"`
+	Lines uint64 `json:"lines"`
+	Size  int64  `json:"size"` // In bytes
+}
+
+// WordlistStore defines the interface for keeping the wordlists uploaded to the coordinator.
+// Lines are numbered from 0.
+type WordlistStore interface {
+	// Save stores a new wordlist read from content.
+	Save(name string, content io.Reader) (Wordlist, error)
+
+	// Get returns the description of a wordlist.
+	Get(name string) (Wordlist, bool)
+
+	// List returns every wordlist, sorted by name.
+	List() []Wordlist
+
+	// ReadLines writes the lines first to last of a wordlist, both included, each followed by a newline.
+	ReadLines(name string, first, last uint64, w io.Writer) error
+}
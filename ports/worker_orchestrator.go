@@ -0,0 +1,43 @@
+package ports
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrOrchestratorUnavailable is wrapped by the errors returned while the orchestrator cannot be reached.
+// Callers should keep the current workers and try again later.
+var ErrOrchestratorUnavailable = errors.New("orchestrator unavailable")
+
+// WorkerInfo describes a worker replica managed by the orchestrator.
+type WorkerInfo struct {
+	ID          string `json:"id"`          // Identifier of the replica within the orchestrator (e.g. Swarm task ID)
+	ContainerID string `json:"containerId"` // Identifier of the running container or process
+	Slot        int    `json:"slot"`        // Position of the replica within the service, starting at 1
+	Node        string `json:"node"`
+	State       string `json:"state"`
+}
+
+// WorkerRunning is the State of a replica whose worker is running.
+const WorkerRunning = "running"
+
+// WorkerOrchestrator defines the interface for managing the replicas of the worker service.
+type WorkerOrchestrator interface {
+	// Scale sets the number of worker replicas.
+	Scale(ctx context.Context, replicas uint64) error
+
+	// Replicas returns the number of worker replicas currently requested.
+	Replicas(ctx context.Context) (uint64, error)
+
+	// ListWorkers returns the worker replicas currently known to the orchestrator.
+	ListWorkers(ctx context.Context) ([]WorkerInfo, error)
+
+	// RestartWorker restarts the worker running in the given container.
+	RestartWorker(ctx context.Context, containerID string) error
+
+	// GetWorkerLogs returns the logs of the worker running in the given container.
+	GetWorkerLogs(ctx context.Context, containerID string) (string, error)
+
+	// GetWorkerIPs returns the IP addresses of the running workers.
+	GetWorkerIPs(ctx context.Context) ([]string, error)
+}
@@ -0,0 +1,117 @@
+// Package tracing follows each job with OpenTelemetry, from its submission to the delivery of its result.
+// Every span of a job belongs to the trace of its root "job" span and carries its job.id attribute.
+// The trace of a job is identified by the traceparent saved with it, so it survives asynchronous steps.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// JobID is the attribute linking a span to its job.
+const JobID = attribute.Key("job.id")
+
+// Tracer returns the tracer of the coordinator. Spans are dropped until Setup installs an exporter.
+func Tracer() trace.Tracer {
+	return otel.Tracer("theleaddestroyer")
+}
+
+// Setup installs the span exporter named by the TRACING_EXPORTER environment variable:
+// "stdout" prints spans as JSON, "otlp" sends them over HTTP to the collector set by the standard
+// OTEL_EXPORTER_OTLP_* variables, and "none" (the default) disables tracing.
+// The returned function flushes the pending spans.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch name := os.Getenv("TRACING_EXPORTER"); name {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown TRACING_EXPORTER %q, expected none, stdout or otlp", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create span exporter: %v", err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the default service name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "theleaddestroyer")),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe tracing resource: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// jobSpans keeps the root span of every unfinished job, to end it once the job finishes.
+var jobSpans sync.Map // Maps job IDs to their root span
+
+// StartJob starts the root span of a new job, as a child of the span in ctx if any.
+// It returns the W3C traceparent identifying the span, saved with the job so its later spans join the trace.
+func StartJob(ctx context.Context, hash string) (trace.Span, string) {
+	ctx, span := Tracer().Start(ctx, "job", trace.WithAttributes(attribute.String("job.hash", hash)))
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return span, carrier.Get("traceparent")
+}
+
+// TrackJob remembers the root span of a job until EndJob.
+func TrackJob(jobID string, span trace.Span) {
+	span.SetAttributes(JobID.String(jobID))
+	jobSpans.Store(jobID, span)
+}
+
+// EndJob ends the root span of a finished job with its final state.
+func EndJob(jobID, state, reason string) {
+	value, ok := jobSpans.LoadAndDelete(jobID)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	span.SetAttributes(attribute.String("job.state", state))
+	if reason != "" {
+		span.SetStatus(codes.Error, reason)
+	}
+	span.End()
+}
+
+// StartJobSpan starts a span of a job, within the trace identified by its traceparent.
+func StartJobSpan(traceParent, jobID, name string, attributes ...attribute.KeyValue) trace.Span {
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{"traceparent": traceParent})
+	_, span := Tracer().Start(ctx, name, trace.WithAttributes(append(attributes, JobID.String(jobID))...))
+	return span
+}
+
+// End ends a span, recording the error if any.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}